@@ -0,0 +1,142 @@
+package tldextract
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+// CacheMetaSuffix - appended to the cache file name to form its sidecar metadata file name
+const CacheMetaSuffix = ".meta"
+
+// CacheMeta - sidecar metadata recording the HTTP validators of each source the cache file was built from
+type CacheMeta struct {
+	Sources map[string]SourceMeta `json:"sources"`
+}
+
+// SourceMeta - validators returned by one source URL, sent back as If-None-Match / If-Modified-Since
+type SourceMeta struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// LoadCacheMeta - read the sidecar metadata of cache file fqdn
+func LoadCacheMeta(fqdn string) (CacheMeta, error) {
+	meta := CacheMeta{Sources: make(map[string]SourceMeta)}
+	data, err := ReadFile(fqdn + CacheMetaSuffix)
+	if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return CacheMeta{Sources: make(map[string]SourceMeta)}, fmt.Errorf("cache metadata '%s': %w", fqdn+CacheMetaSuffix, err)
+	}
+	if meta.Sources == nil {
+		meta.Sources = make(map[string]SourceMeta)
+	}
+	return meta, nil
+}
+
+// WriteCacheMeta - write the sidecar metadata of cache file fqdn
+func WriteCacheMeta(fqdn string, meta CacheMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFile(fqdn+CacheMetaSuffix, data)
+}
+
+// downloadSources - download urls, sending the validators in meta, and merge the unique rows of the sources
+// passing v into a rule set. notModified reports that no source changed and at least one answered 304, in
+// which case the map is nil. The error of the last failed source is returned when none was usable.
+func downloadSources(ctx context.Context, client *http.Client, urls []string, meta CacheMeta, v Validation) (*RuleSet, CacheMeta, bool, error) {
+	uList := NewRuleSet()
+	newMeta := CacheMeta{Sources: make(map[string]SourceMeta)}
+	unchanged := []string{}
+	var lastErr error
+	for _, url := range urls {
+		if ctx.Err() != nil {
+			break
+		}
+		data, sourceMeta, notModified, err := fetchList(ctx, client, url, meta.Sources[url])
+		if err == nil && !notModified {
+			err = v.ValidateSource(url, string(data))
+		}
+		if err != nil {
+			// nothing good, move to next file
+			lastErr = err
+			continue
+		}
+		if notModified {
+			unchanged = append(unchanged, url)
+			newMeta.Sources[url] = meta.Sources[url]
+			continue
+		}
+		newMeta.Sources[url] = sourceMeta
+		// Normalize buffer and add lines to unique list
+		uList.Merge(ParseRules(url, string(data)))
+	}
+	if uList.Len() <= 0 {
+		notModified := len(unchanged) > 0
+		if notModified {
+			lastErr = nil
+		}
+		return nil, newMeta, notModified, lastErr
+	}
+
+	// The cache holds the union of all sources, so unchanged ones are needed again to rebuild it
+	for _, url := range unchanged {
+		data, sourceMeta, _, err := fetchList(ctx, client, url, SourceMeta{})
+		if err == nil {
+			err = v.ValidateSource(url, string(data))
+		}
+		if err != nil {
+			delete(newMeta.Sources, url)
+			continue
+		}
+		newMeta.Sources[url] = sourceMeta
+		uList.Merge(ParseRules(url, string(data)))
+	}
+	return uList, newMeta, false, nil
+}
+
+// fetch - conditional GET of url, notModified is set on a 304 answer to the validators in sourceMeta
+func fetch(ctx context.Context, client *http.Client, url string, sourceMeta SourceMeta) ([]byte, SourceMeta, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return []byte{}, SourceMeta{}, false, err
+	}
+	if sourceMeta.ETag != "" {
+		req.Header.Set("If-None-Match", sourceMeta.ETag)
+	}
+	if sourceMeta.LastModified != "" {
+		req.Header.Set("If-Modified-Since", sourceMeta.LastModified)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return []byte{}, SourceMeta{}, false, err
+	}
+
+	defer resp.Body.Close()
+
+	conditional := sourceMeta.ETag != "" || sourceMeta.LastModified != ""
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		data, err := ioutil.ReadAll(resp.Body)
+		return data, SourceMeta{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}, false, err
+	case resp.StatusCode == http.StatusNotModified && conditional:
+		return []byte{}, sourceMeta, true, nil
+	}
+
+	return []byte{}, SourceMeta{}, false, fmt.Errorf("HTTP Status Code: %d returned", resp.StatusCode)
+}
+
+// touchCacheFile - mark cache file fqdn as fresh after every source confirmed it unchanged
+func touchCacheFile(fqdn string) error {
+	now := time.Now()
+	return os.Chtimes(fqdn, now, now)
+}
@@ -0,0 +1,120 @@
+package tldextract
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type conditionalServer struct {
+	*httptest.Server
+	body         string
+	etag         string
+	lastModified string
+	full         int
+	notModified  int
+}
+
+func newConditionalServer(t *testing.T, body string, etag string, lastModified string) *conditionalServer {
+	cs := &conditionalServer{body: body, etag: etag, lastModified: lastModified}
+	cs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (cs.etag != "" && r.Header.Get("If-None-Match") == cs.etag) ||
+			(cs.lastModified != "" && r.Header.Get("If-Modified-Since") == cs.lastModified) {
+			cs.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		cs.full++
+		if cs.etag != "" {
+			w.Header().Set("ETag", cs.etag)
+		}
+		if cs.lastModified != "" {
+			w.Header().Set("Last-Modified", cs.lastModified)
+		}
+		fmt.Fprint(w, cs.body)
+	}))
+	t.Cleanup(cs.Close)
+	return cs
+}
+
+func Test_CreateNewCacheFile_etag(t *testing.T) {
+	assert := assert.New(t)
+	server := newConditionalServer(t, "com\n", `"v1"`, "")
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+
+	actual, err := CreateNewCacheFile(cacheFile, []string{server.URL}, 5)
+	assert.Nil(err, "Error nil")
+	assert.Contains(GetKeys(actual), "com", "downloaded")
+	meta, err := LoadCacheMeta(cacheFile)
+	assert.Nil(err, "metadata written")
+	assert.Equal(SourceMeta{ETag: `"v1"`}, meta.Sources[server.URL], "ETag recorded")
+
+	old := time.Now().Add(-time.Hour)
+	assert.Nil(os.Chtimes(cacheFile, old, old), "age cache file")
+	actual, err = CreateNewCacheFile(cacheFile, []string{server.URL}, 5)
+	assert.Nil(err, "304 is not an error")
+	assert.Contains(GetKeys(actual), "com", "cached list returned")
+	assert.Equal(1, server.full, "one full download")
+	assert.Equal(1, server.notModified, "one conditional hit")
+	info, _ := os.Stat(cacheFile)
+	assert.True(info.ModTime().After(old), "cache file marked fresh")
+
+	server.body, server.etag = "com\norg\n", `"v2"`
+	actual, err = CreateNewCacheFile(cacheFile, []string{server.URL}, 5)
+	assert.Nil(err, "Error nil")
+	assert.Contains(GetKeys(actual), "org", "changed list downloaded")
+	meta, _ = LoadCacheMeta(cacheFile)
+	assert.Equal(`"v2"`, meta.Sources[server.URL].ETag, "ETag updated")
+}
+
+func Test_CreateNewCacheFile_last_modified(t *testing.T) {
+	assert := assert.New(t)
+	server := newConditionalServer(t, "com\n", "", "Mon, 02 Jan 2006 15:04:05 GMT")
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+
+	_, err := CreateNewCacheFile(cacheFile, []string{server.URL}, 5)
+	assert.Nil(err, "Error nil")
+	_, err = CreateNewCacheFile(cacheFile, []string{server.URL}, 5)
+	assert.Nil(err, "Error nil")
+
+	assert.Equal(1, server.full, "one full download")
+	assert.Equal(1, server.notModified, "one conditional hit")
+}
+
+func Test_CreateNewCacheFile_partial_change(t *testing.T) {
+	assert := assert.New(t)
+	server1 := newConditionalServer(t, "com\n", `"a1"`, "")
+	server2 := newConditionalServer(t, "net\n", `"b1"`, "")
+	urls := []string{server1.URL, server2.URL}
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+
+	_, err := CreateNewCacheFile(cacheFile, urls, 5)
+	assert.Nil(err, "Error nil")
+
+	server2.body, server2.etag = "org\n", `"b2"`
+	actual, err := CreateNewCacheFile(cacheFile, urls, 5)
+	assert.Nil(err, "Error nil")
+	assert.Equal(map[string]Section{"com": SectionICANN, "org": SectionICANN}, ruleSections(actual), "unchanged source kept, changed source replaced")
+	assert.Equal(2, server1.full, "unchanged source downloaded again to rebuild the cache")
+}
+
+func Test_CreateNewCacheFile_missing_cache(t *testing.T) {
+	assert := assert.New(t)
+	server := newConditionalServer(t, "com\n", `"v1"`, "")
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+
+	_, err := CreateNewCacheFile(cacheFile, []string{server.URL}, 5)
+	assert.Nil(err, "Error nil")
+	assert.Nil(os.Remove(cacheFile), "remove cache file")
+
+	actual, err := CreateNewCacheFile(cacheFile, []string{server.URL}, 5)
+	assert.Nil(err, "Error nil")
+	assert.Contains(GetKeys(actual), "com", "downloaded")
+	assert.Equal(2, server.full, "no conditional request without a cache file")
+}
@@ -0,0 +1,75 @@
+// Command tldcache lists the saved versions of a tldextract cache file and rolls it back to one of them.
+//
+// Usage:
+//
+//	tldcache [-file tld.cache] list
+//	tldcache [-file tld.cache] rollback <id>
+//
+// Versions are saved by extractors created with tldextract.WithHistory.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mjd2021usa/tldextract"
+)
+
+func main() {
+	file := flag.String("file", "tld.cache", "cache file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: tldcache [-file tld.cache] list | rollback <id>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var err error
+	switch {
+	case flag.NArg() == 1 && flag.Arg(0) == "list":
+		err = list(*file)
+	case flag.NArg() == 2 && flag.Arg(0) == "rollback":
+		err = rollback(*file, flag.Arg(1))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tldcache: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func list(file string) error {
+	entries, err := tldextract.ListHistory(file)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSAVED\tRULES\tVERSION\tSOURCES")
+	for _, entry := range entries {
+		rules, version, sources := "-", "-", "-"
+		if entry.HasHeader {
+			rules = fmt.Sprint(entry.Header.Rules)
+			if entry.Header.Version != "" {
+				version = entry.Header.Version
+			}
+			if len(entry.Header.Sources) > 0 {
+				sources = strings.Join(entry.Header.Sources, ",")
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.ID, entry.Saved.Local().Format(time.RFC3339), rules, version, sources)
+	}
+	return w.Flush()
+}
+
+func rollback(file string, id string) error {
+	if err := tldextract.RollbackCache(file, id); err != nil {
+		return err
+	}
+	fmt.Printf("tldcache: '%s' rolled back to %s\n", file, id)
+	return nil
+}
@@ -0,0 +1,102 @@
+package tldextract
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression - encoding of written cache files, loaders detect it by the magic bytes
+type Compression int
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionZstd
+)
+
+// MaxDecompressedSize - limit of a decompressed list or cache file, far above the public suffix list
+const MaxDecompressedSize = 32 << 20
+
+// ErrListTooLarge - a compressed list or cache file decompresses to more than MaxDecompressedSize
+var ErrListTooLarge = errors.New("decompressed list too large")
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Compress - data encoded with c
+func Compress(data []byte, c Compression) ([]byte, error) {
+	switch c {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		zw, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer zw.Close()
+		return zw.EncodeAll(data, nil), nil
+	}
+	return nil, fmt.Errorf("unknown compression %d", c)
+}
+
+// Decompress - data decoded when it starts with the gzip or zstd magic bytes, data itself otherwise
+func Decompress(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		decoded, err := ioutil.ReadAll(io.LimitReader(zr, MaxDecompressedSize+1))
+		if err == nil && len(decoded) > MaxDecompressedSize {
+			err = ErrListTooLarge
+		}
+		return decoded, err
+	case bytes.HasPrefix(data, zstdMagic):
+		zr, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxDecompressedSize), zstd.WithDecoderMaxWindow(MaxDecompressedSize))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		decoded, err := zr.DecodeAll(data, nil)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+			err = ErrListTooLarge
+		}
+		return decoded, err
+	}
+	return data, nil
+}
+
+// fetchList - fetch a list like fetch, decompressing gzip and zstd bodies such as ".gz" list URLs
+func fetchList(ctx context.Context, client *http.Client, url string, sourceMeta SourceMeta) ([]byte, SourceMeta, bool, error) {
+	data, newMeta, notModified, err := fetch(ctx, client, url, sourceMeta)
+	if err != nil || notModified {
+		return data, newMeta, notModified, err
+	}
+	data, err = Decompress(data)
+	if err != nil {
+		return nil, SourceMeta{}, false, fmt.Errorf("'%s': %w", url, err)
+	}
+	return data, newMeta, false, nil
+}
@@ -0,0 +1,94 @@
+package tldextract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Compress_round_trip(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		compression Compression
+		magic       []byte
+	}{
+		{compression: CompressionNone, magic: []byte("//")},
+		{compression: CompressionGzip, magic: gzipMagic},
+		{compression: CompressionZstd, magic: zstdMagic},
+	}
+	for _, test := range tests {
+		msg := fmt.Sprintf("compression %d", test.compression)
+		data, err := Compress([]byte(testListBody), test.compression)
+		assert.Nil(err, msg)
+		assert.True(bytes.HasPrefix(data, test.magic), msg)
+		actual, err := Decompress(data)
+		assert.Nil(err, msg)
+		assert.Equal(testListBody, string(actual), msg)
+	}
+
+	_, err := Compress([]byte(testListBody), Compression(42))
+	assert.NotNil(err, "unknown compression")
+	_, err = Decompress(append(append([]byte{}, gzipMagic...), "truncated"...))
+	assert.NotNil(err, "corrupt gzip")
+}
+
+func Test_Decompress_too_large(t *testing.T) {
+	assert := assert.New(t)
+	data := make([]byte, MaxDecompressedSize+1)
+	for _, compression := range []Compression{CompressionGzip, CompressionZstd} {
+		msg := fmt.Sprintf("compression %d", compression)
+		compressed, err := Compress(data, compression)
+		assert.Nil(err, msg)
+		_, err = Decompress(compressed)
+		assert.True(errors.Is(err, ErrListTooLarge), msg)
+
+		compressed, _ = Compress(data[:MaxDecompressedSize], compression)
+		actual, err := Decompress(compressed)
+		assert.Nil(err, msg)
+		assert.Len(actual, MaxDecompressedSize, msg)
+	}
+}
+
+func Test_NewWithOptions_compression(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := newListServer(t, testListBody)
+
+	for compression, magic := range map[Compression][]byte{CompressionGzip: gzipMagic, CompressionZstd: zstdMagic} {
+		msg := fmt.Sprintf("compression %d", compression)
+		cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+		_, err := NewWithOptions(
+			WithCacheFile(cacheFile),
+			WithURLs(server.URL),
+			WithHTTPClient(server.Client()),
+			WithCompression(compression),
+			WithEmbeddedFallback(false),
+		)
+		assert.Nil(err, msg)
+
+		data, _ := ReadFile(cacheFile)
+		assert.True(bytes.HasPrefix(data, magic), msg+" written compressed")
+		rules, err := LoadCacheFile(cacheFile)
+		assert.Nil(err, msg)
+		assert.Equal(SectionPrivate, ruleSections(rules)["blogspot.com"], msg+" loaded transparently")
+	}
+}
+
+func Test_DownloadUrls2List_gz(t *testing.T) {
+	assert := assert.New(t)
+	compressed, _ := Compress([]byte(testListBody), CompressionGzip)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Write(compressed)
+	}))
+	defer server.Close()
+
+	actual := DownloadUrls2List([]string{server.URL + "/public_suffix_list.dat.gz"}, 5)
+
+	assert.Equal(map[string]Section{"com": SectionICANN, "example": SectionICANN, "blogspot.com": SectionPrivate}, ruleSections(actual), "rules")
+}
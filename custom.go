@@ -0,0 +1,75 @@
+package tldextract
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// AddRule - add a PRIVATE section rule such as "corp.example" or "*.sites.example" to the rules in use.
+// Added rules take precedence over the public list and are kept across refreshes. Safe to call while
+// other goroutines Extract.
+func (tlde *TLDExtract) AddRule(rule string) error {
+	if !IsValidRule(rule) {
+		return fmt.Errorf("invalid rule '%s'", rule)
+	}
+	rules := NewRuleSet()
+	rules.Add(NewRule(rule, SectionPrivate, runtimeSource, 0))
+	return tlde.addRules(rules)
+}
+
+// AddRulesFromReader - add every rule in r, in public suffix list format, as AddRule does. Rules outside
+// the section markers are PRIVATE. Nothing is added when a line is not a valid rule.
+func (tlde *TLDExtract) AddRulesFromReader(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	source := readerSource
+	if named, ok := r.(interface{ Name() string }); ok {
+		source = named.Name()
+	}
+	if err := (Validation{CheckSyntax: true}).ValidateSource(source, string(data)); err != nil {
+		return err
+	}
+	return tlde.addRules(parseRules(source, string(data), SectionPrivate))
+}
+
+// RemoveRule - stop applying rule, whether it came from the public list or was added, also after
+// refreshes. Reports whether the rule was in use.
+func (tlde *TLDExtract) RemoveRule(rule string) bool {
+	if tlde.state == nil {
+		return false
+	}
+	text := NewRule(rule, SectionPrivate, runtimeSource, 0).Text
+	state := tlde.state
+	state.rulesMu.Lock()
+	defer state.rulesMu.Unlock()
+
+	current := state.snapshot()
+	if _, found := current.rules.Get(text); !found {
+		return false
+	}
+	state.custom.Remove(text)
+	state.removed[text] = true
+	state.publish(current.loadedAt)
+	return true
+}
+
+func (tlde *TLDExtract) addRules(rules *RuleSet) error {
+	if tlde.state == nil {
+		return fmt.Errorf("rules can only be changed on an extractor created by a constructor")
+	}
+	state := tlde.state
+	state.rulesMu.Lock()
+	defer state.rulesMu.Unlock()
+
+	for _, rule := range rules.Rules() {
+		// The latest addition wins
+		state.custom.Remove(rule.Text)
+		state.custom.Add(rule)
+		delete(state.removed, rule.Text)
+	}
+	state.publish(state.snapshot().loadedAt)
+	return nil
+}
@@ -0,0 +1,96 @@
+package tldextract
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AddRule(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := newListServer(t, testListBody)
+	tld, err := NewWithOptions(WithURLs(server.URL), WithHTTPClient(server.Client()), WithEmbeddedFallback(false))
+	assert.Nil(err, "Error nil")
+	icann := tld.ICANNOnly()
+
+	assert.Nil(tld.AddRule("corp.example"), "add rule")
+	assert.Nil(tld.AddRule("*.svc.cluster.local"), "add wildcard rule")
+	assert.NotNil(tld.AddRule("<html>"), "invalid rule")
+	assert.NotNil(tld.AddRule("*"), "bare wildcard")
+
+	actual := tld.Extract("wiki.team.corp.example")
+	assertResult(t, "wiki.team.corp.example", &Result{Flag: Domain, SubDomain: "wiki", Domain: "team", Tld: "corp.example"}, actual, "added rule")
+	assert.True(actual.IsPrivate, "added rules are private")
+	assert.Equal(runtimeSource, actual.Rule.Source, "source")
+	actual = tld.Extract("api.default.svc.cluster.local")
+	assertResult(t, "api.default.svc.cluster.local", &Result{Flag: Domain, Domain: "api", Tld: "default.svc.cluster.local"}, actual, "added wildcard rule")
+	actual = icann.Extract("wiki.team.corp.example")
+	assertResult(t, "wiki.team.corp.example", &Result{Flag: Domain, SubDomain: "wiki.team", Domain: "corp", Tld: "example"}, actual, "ICANN only copy ignores added rules")
+
+	assert.True(tld.RemoveRule("blogspot.com"), "remove list rule")
+	assert.True(tld.RemoveRule("*.svc.cluster.local"), "remove added rule")
+	assert.False(tld.RemoveRule("not.a.rule"), "unknown rule")
+	assertResult(t, "foo.blogspot.com", &Result{Flag: Domain, SubDomain: "foo", Domain: "blogspot", Tld: "com"}, tld.Extract("foo.blogspot.com"), "removed list rule")
+
+	assert.Nil(tld.Refresh(), "refresh")
+	assertResult(t, "wiki.team.corp.example", &Result{Flag: Domain, SubDomain: "wiki", Domain: "team", Tld: "corp.example"}, tld.Extract("wiki.team.corp.example"), "added rule survives refresh")
+	assertResult(t, "foo.blogspot.com", &Result{Flag: Domain, SubDomain: "foo", Domain: "blogspot", Tld: "com"}, tld.Extract("foo.blogspot.com"), "removal survives refresh")
+
+	assert.Nil(tld.AddRule("blogspot.com"), "add removed rule again")
+	assertResult(t, "foo.blogspot.com", &Result{Flag: Domain, Domain: "foo", Tld: "blogspot.com"}, tld.Extract("foo.blogspot.com"), "rule added again")
+}
+
+func Test_AddRulesFromReader(t *testing.T) {
+	assert := assert.New(t)
+	tld, err := NewWithOptions(WithCacheFile("test/tld.cache"), WithRefreshPolicy(RefreshNever))
+	assert.Nil(err, "Error nil")
+
+	err = tld.AddRulesFromReader(strings.NewReader("// platform domains\nsites.example\n\n// ===BEGIN ICANN DOMAINS===\nexample\n// ===END ICANN DOMAINS===\n"))
+	assert.Nil(err, "add rules")
+	actual := tld.Extract("shop.sites.example")
+	assertResult(t, "shop.sites.example", &Result{Flag: Domain, Domain: "shop", Tld: "sites.example"}, actual, "rule from reader")
+	assert.True(actual.IsPrivate, "rules outside markers are private")
+	assert.Equal(Rule{Text: "sites.example", Type: RuleNormal, Section: SectionPrivate, Source: readerSource, Line: 2}, *actual.Rule, "provenance")
+	actual = tld.Extract("www.example")
+	assert.False(actual.IsPrivate, "rules inside ICANN markers are ICANN")
+
+	err = tld.AddRulesFromReader(strings.NewReader("one.example\n<html>\n"))
+	assert.NotNil(err, "invalid line")
+	assertResult(t, "a.one.example", &Result{Flag: Domain, SubDomain: "a", Domain: "one", Tld: "example"}, tld.Extract("a.one.example"), "nothing added")
+
+	file := filepath.Join(t.TempDir(), "custom.dat")
+	assert.Nil(WriteFile(file, []byte("two.example\n")), "write rules")
+	f, err := os.Open(file)
+	assert.Nil(err, "open rules")
+	defer f.Close()
+	assert.Nil(tld.AddRulesFromReader(f), "add rules from file")
+	assert.Equal(file, tld.Extract("a.two.example").Rule.Source, "file name is the source")
+}
+
+func Test_AddRule_concurrent(t *testing.T) {
+	tld, err := NewWithOptions(WithCacheFile("test/tld.cache"), WithRefreshPolicy(RefreshNever))
+	assert.Nil(t, err, "Error nil")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				tld.Extract("wiki.team.corp.example")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				tld.AddRule("corp.example")
+				tld.RemoveRule("corp.example")
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -23,7 +23,7 @@ func main() {
 	domainRoot := "fruitloops"
 	subDomainRoot := "mr.ozzy"
 
-	for key, _ := range uList {
+	for _, key := range tldextract.GetKeys(uList) {
 		exceptionRule := key[0] == '!'
 		if exceptionRule {
 			//key = key[1:]
@@ -0,0 +1,30 @@
+package tldextract
+
+import (
+	"context"
+	"time"
+)
+
+// LockSuffix - appended to the cache file name to form the advisory lock file held during a refresh
+const LockSuffix = ".lock"
+
+// lockRetryInterval - how often a held lock is polled while waiting for it
+var lockRetryInterval = 50 * time.Millisecond
+
+// LockFile - take the advisory lock at path, waiting until it is free or ctx is done.
+// Call the returned function to release it.
+func LockFile(ctx context.Context, path string) (func(), error) {
+	return lockFile(ctx, path)
+}
+
+// waitLock - sleep one retry interval, returning early with the error when ctx is done
+func waitLock(ctx context.Context) error {
+	timer := time.NewTimer(lockRetryInterval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
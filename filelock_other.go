@@ -0,0 +1,36 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package tldextract
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// staleLockAge - a lock file older than this is assumed left behind by a dead process
+const staleLockAge = 10 * time.Minute
+
+// lockFile - exclusive create of the lock file, removed again on release
+func lockFile(ctx context.Context, path string) (func(), error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() {
+				os.Remove(path)
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(path)
+			continue
+		}
+		if err := waitLock(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
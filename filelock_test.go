@@ -0,0 +1,86 @@
+package tldextract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LockFile(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "tld.cache.lock")
+
+	unlock, err := LockFile(context.Background(), path)
+	assert.Nil(err, "Error nil")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*lockRetryInterval)
+	defer cancel()
+	_, err = LockFile(ctx, path)
+	assert.True(errors.Is(err, context.DeadlineExceeded), "held lock waits until ctx is done")
+
+	unlock()
+	unlock, err = LockFile(context.Background(), path)
+	assert.Nil(err, "released lock can be taken")
+	unlock()
+}
+
+func Test_WriteFile_atomic(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	fqfn := filepath.Join(dir, "tld.cache")
+
+	assert.Nil(WriteFile(fqfn, []byte("com\n")), "first write")
+	assert.Nil(WriteFile(fqfn, []byte("com\norg\n")), "overwrite")
+
+	data, err := ReadFile(fqfn)
+	assert.Nil(err, "Error nil")
+	assert.Equal("com\norg\n", string(data), "new content")
+	info, _ := os.Stat(fqfn)
+	assert.Equal(os.FileMode(0644), info.Mode().Perm(), "permissions")
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(files, 1, "no temporary files left behind")
+}
+
+func Test_NewWithOptions_single_download(t *testing.T) {
+	assert := assert.New(t)
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, testListBody)
+	}))
+	defer server.Close()
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for idx := range errs {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			_, errs[idx] = NewWithOptions(
+				WithCacheFile(cacheFile),
+				WithURLs(server.URL),
+				WithRefreshPolicy(RefreshAlways),
+				WithEmbeddedFallback(false),
+			)
+		}(idx)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.Nil(err, "Error nil")
+	}
+	assert.Equal(int32(1), atomic.LoadInt32(&hits), "one process downloads, the others use its cache file")
+}
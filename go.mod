@@ -1,5 +1,16 @@
 module github.com/mjd2021usa/tldextract
 
-go 1.16
+go 1.22
 
-require github.com/stretchr/testify v1.7.0
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/stretchr/testify v1.7.0
+	golang.org/x/net v0.17.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
@@ -0,0 +1,111 @@
+package tldextract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheHeaderTitle - first line of a cache file written with a header
+const CacheHeaderTitle = "// tldextract cache file"
+
+// CacheHeader - self describing header at the top of a cache file, in "// key: value" comment lines
+type CacheHeader struct {
+	Fetched time.Time
+	Sources []string
+	// Version and Commit are the upstream list's "VERSION" and "COMMIT" comments
+	Version string
+	Commit  string
+	Rules   int
+	// SHA256 is the hex digest of the CacheLines of the rules, joined by newlines
+	SHA256 string
+}
+
+// NewCacheHeader - header describing list, fetched from sources at fetched
+func NewCacheHeader(list *RuleSet, fetched time.Time, sources []string) CacheHeader {
+	sum := sha256.Sum256([]byte(strings.Join(CacheLines(list), "\n")))
+	return CacheHeader{
+		Fetched: fetched.UTC().Truncate(time.Second),
+		Sources: sources,
+		Version: list.Version,
+		Commit:  list.Commit,
+		Rules:   list.Len(),
+		SHA256:  hex.EncodeToString(sum[:]),
+	}
+}
+
+// Lines - the header as comment lines, empty fields are left out
+func (h CacheHeader) Lines() []string {
+	lines := []string{CacheHeaderTitle}
+	if !h.Fetched.IsZero() {
+		lines = append(lines, "// fetched: "+h.Fetched.UTC().Format(time.RFC3339))
+	}
+	for _, source := range h.Sources {
+		lines = append(lines, "// source: "+source)
+	}
+	if h.Version != "" {
+		lines = append(lines, "// version: "+h.Version)
+	}
+	if h.Commit != "" {
+		lines = append(lines, "// commit: "+h.Commit)
+	}
+	lines = append(lines, "// rules: "+strconv.Itoa(h.Rules))
+	lines = append(lines, "// sha256: "+h.SHA256)
+	return lines
+}
+
+// ParseCacheHeader - header at the top of buffer, false when buffer has none
+func ParseCacheHeader(buffer string) (CacheHeader, bool) {
+	lines := strings.Split(buffer, "\n")
+	if strings.TrimSpace(lines[0]) != CacheHeaderTitle {
+		return CacheHeader{}, false
+	}
+	h := CacheHeader{}
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "//") {
+			break
+		}
+		key, value := commentField(line)
+		switch key {
+		case "fetched":
+			h.Fetched, _ = time.Parse(time.RFC3339, value)
+		case "source":
+			h.Sources = append(h.Sources, value)
+		case "version":
+			h.Version = value
+		case "commit":
+			h.Commit = value
+		case "rules":
+			h.Rules, _ = strconv.Atoi(value)
+		case "sha256":
+			h.SHA256 = value
+		default:
+			// The header ends at the first section marker
+			return h, true
+		}
+	}
+	return h, true
+}
+
+// CacheFileBytes - cache file contents for list: the header followed by the CacheLines. Equal rules,
+// sources and fetch time give equal bytes.
+func CacheFileBytes(list *RuleSet, fetched time.Time, sources []string) []byte {
+	lines := append(NewCacheHeader(list, fetched, sources).Lines(), CacheLines(list)...)
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// ReadCacheHeader - header of cache file fqdn, decompressed, false when it has none
+func ReadCacheHeader(fqdn string) (CacheHeader, bool, error) {
+	data, err := ReadFile(fqdn)
+	if err == nil {
+		data, err = Decompress(data)
+	}
+	if err != nil {
+		return CacheHeader{}, false, err
+	}
+	h, found := ParseCacheHeader(string(data))
+	return h, found, nil
+}
@@ -0,0 +1,71 @@
+package tldextract
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testUpstreamBody = `// This Source Code Form is subject to the terms of the Mozilla Public
+// VERSION: 2026-10-01_09-00-00_UTC
+// COMMIT: 0123456789abcdef
+` + testListBody
+
+func Test_CacheFileBytes(t *testing.T) {
+	assert := assert.New(t)
+	fetched := time.Date(2026, 10, 17, 8, 30, 0, 0, time.UTC)
+
+	first := CacheFileBytes(ParseRules("a", testUpstreamBody), fetched, []string{"https://list"})
+	second := CacheFileBytes(ParseRules("b", strings.Replace(testUpstreamBody, "com\nexample", "example\ncom", 1)), fetched, []string{"https://list"})
+	assert.Equal(string(first), string(second), "reproducible bytes")
+
+	lines := strings.Split(string(first), "\n")
+	assert.Equal([]string{
+		CacheHeaderTitle,
+		"// fetched: 2026-10-17T08:30:00Z",
+		"// source: https://list",
+		"// version: 2026-10-01_09-00-00_UTC",
+		"// commit: 0123456789abcdef",
+		"// rules: 3",
+	}, lines[:6], "header")
+	assert.Equal([]string{"// " + ICANNBeginMarker, "com", "example", "// " + ICANNEndMarker}, lines[7:11], "sorted rules with section markers")
+
+	header, found := ParseCacheHeader(string(first))
+	assert.True(found, "header found")
+	assert.Equal(NewCacheHeader(ParseRules("a", testUpstreamBody), fetched, []string{"https://list"}), header, "round trip")
+
+	rules := ParseRules("cache", string(first))
+	assert.Equal(3, rules.Len(), "header is not rules")
+	assert.Equal("2026-10-01_09-00-00_UTC", rules.Version, "version kept")
+
+	changed := NewCacheHeader(ParseRules("c", testUpstreamBody+"net\n"), fetched, nil)
+	assert.NotEqual(header.SHA256, changed.SHA256, "digest detects drift")
+
+	_, found = ParseCacheHeader(testListBody)
+	assert.False(found, "no header")
+}
+
+func Test_CreateNewCacheFile_header(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := newListServer(t, testUpstreamBody)
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+
+	before := time.Now().Add(-time.Second)
+	_, err := CreateNewCacheFile(cacheFile, []string{server.URL}, 5)
+	assert.Nil(err, "Error nil")
+
+	header, found, err := ReadCacheHeader(cacheFile)
+	assert.Nil(err, "Error nil")
+	assert.True(found, "header written")
+	assert.Equal([]string{server.URL}, header.Sources, "sources")
+	assert.Equal("0123456789abcdef", header.Commit, "commit")
+	assert.Equal(3, header.Rules, "rule count")
+	assert.True(header.Fetched.After(before), "fetch time")
+
+	rules, err := LoadCacheFile(cacheFile)
+	assert.Nil(err, "Error nil")
+	assert.Equal(NewCacheHeader(rules, header.Fetched, header.Sources).SHA256, header.SHA256, "digest of loaded rules")
+}
@@ -0,0 +1,208 @@
+package tldextract
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HistorySuffix - appended to the cache file name to form the directory holding its previous versions
+const HistorySuffix = ".history"
+
+// historyTimeFormat - sortable save time at the start of a history ID
+const historyTimeFormat = "20060102T150405.000000000Z"
+
+// HistoryEntry - one saved version of a cache file, newest first in ListHistory
+type HistoryEntry struct {
+	// ID is "<save time>-<digest prefix>", used to pin or roll back to the version
+	ID    string
+	Path  string
+	Saved time.Time
+	// Header is the cache file header, HasHeader is false for lists stored verbatim
+	Header    CacheHeader
+	HasHeader bool
+}
+
+// ListHistory - the saved versions of cache file fqdn, newest first
+func ListHistory(fqdn string) ([]HistoryEntry, error) {
+	dir := fqdn + HistorySuffix
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []HistoryEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []HistoryEntry{}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || strings.HasSuffix(name, SignatureSuffix) || strings.HasPrefix(name, ".") {
+			continue
+		}
+		idx := strings.Index(name, "-")
+		if idx == -1 {
+			continue
+		}
+		saved, err := time.Parse(historyTimeFormat, name[:idx])
+		if err != nil {
+			continue
+		}
+		entry := HistoryEntry{ID: name, Path: filepath.Join(dir, name), Saved: saved}
+		entry.Header, entry.HasHeader, _ = ReadCacheHeader(entry.Path)
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID > entries[j].ID })
+	return entries, nil
+}
+
+// historyEntry - the saved version id of cache file fqdn
+func historyEntry(fqdn string, id string) (HistoryEntry, error) {
+	entries, err := ListHistory(fqdn)
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return HistoryEntry{}, fmt.Errorf("cache file '%s' has no version '%s'", fqdn, id)
+}
+
+// RollbackCache - replace cache file fqdn with its saved version id, signature included. The sidecar
+// metadata is removed, so the next refresh downloads the list again instead of keeping the rolled back
+// version on a 304 answer; pin the version to keep it.
+func RollbackCache(fqdn string, id string) error {
+	entry, err := historyEntry(fqdn, id)
+	if err != nil {
+		return err
+	}
+	data, err := ReadFile(entry.Path)
+	if err != nil {
+		return err
+	}
+	if signature, err := ReadFile(entry.Path + SignatureSuffix); err == nil {
+		if err := WriteFile(fqdn+SignatureSuffix, signature); err != nil {
+			return err
+		}
+	}
+	if err := WriteFile(fqdn, data); err != nil {
+		return err
+	}
+	// The validators describe the newer list
+	if err := os.Remove(fqdn + CacheMetaSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// saveHistory - keep data, the cache file just written, and its signature as the newest version of
+// fqdn, removing all but the newest keep versions. A version equal to the newest one is not saved again.
+func saveHistory(fqdn string, data []byte, signature []byte, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	digest, err := versionDigest(data)
+	if err != nil {
+		return err
+	}
+	entries, err := ListHistory(fqdn)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 || !strings.HasSuffix(entries[0].ID, "-"+digest) {
+		dir := fqdn + HistorySuffix
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		id := time.Now().UTC().Format(historyTimeFormat) + "-" + digest
+		path := filepath.Join(dir, id)
+		if signature != nil {
+			if err := WriteFile(path+SignatureSuffix, signature); err != nil {
+				return err
+			}
+		}
+		if err := WriteFile(path, data); err != nil {
+			return err
+		}
+		if entries, err = ListHistory(fqdn); err != nil {
+			return err
+		}
+	}
+	for _, entry := range entries[min(keep, len(entries)):] {
+		os.Remove(entry.Path + SignatureSuffix)
+		if err := os.Remove(entry.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionDigest - short digest of the rules in cache file data, ignoring the header fetch time
+func versionDigest(data []byte) (string, error) {
+	data, err := Decompress(data)
+	if err != nil {
+		return "", err
+	}
+	if header, found := ParseCacheHeader(string(data)); found && len(header.SHA256) >= 12 {
+		return header.SHA256[:12], nil
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12], nil
+}
+
+// loadPinned - read the saved version the extractor is pinned to, loadedAt is its save time
+func (o *options) loadPinned(ctx context.Context) (*RuleSet, time.Time, error) {
+	entry, err := historyEntry(o.cacheFile, o.pinned)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	uList, err := loadCacheFile(ctx, entry.Path, o.verifier)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return uList, entry.Saved, nil
+}
+
+// Rollback - pin the extractor to saved version id of its cache file and use that list right away.
+// Refreshes keep the pinned list until Unpin.
+func (tlde *TLDExtract) Rollback(id string) error {
+	return tlde.pin(id)
+}
+
+// Unpin - resume refreshing the list after Rollback or WithPinnedVersion, from the next refresh on
+func (tlde *TLDExtract) Unpin() error {
+	if tlde.options == nil || tlde.state == nil {
+		return fmt.Errorf("no list source configured")
+	}
+	tlde.state.refreshMu.Lock()
+	defer tlde.state.refreshMu.Unlock()
+	tlde.options.pinned = ""
+	return nil
+}
+
+func (tlde *TLDExtract) pin(id string) error {
+	if tlde.options == nil || tlde.state == nil {
+		return fmt.Errorf("no list source configured")
+	}
+	tlde.state.refreshMu.Lock()
+	defer tlde.state.refreshMu.Unlock()
+
+	previous := tlde.options.pinned
+	tlde.options.pinned = id
+	cache, denied, loadedAt, err := tlde.options.loadRules(context.Background())
+	if err != nil {
+		tlde.options.pinned = previous
+		return err
+	}
+	tlde.state.replaceBase(cache, denied, loadedAt)
+	return nil
+}
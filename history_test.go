@@ -0,0 +1,98 @@
+package tldextract
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_History(t *testing.T) {
+	assert := assert.New(t)
+	server := newConditionalServer(t, "com\nexample\n", "", "")
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+
+	tld, err := NewWithOptions(
+		WithCacheFile(cacheFile),
+		WithURLs(server.URL),
+		WithHTTPClient(server.Client()),
+		WithRefreshPolicy(RefreshAlways),
+		WithHistory(2),
+		WithEmbeddedFallback(false),
+	)
+	assert.Nil(err, "Error nil")
+	assert.Nil(tld.Refresh(), "refresh with the same rules")
+	entries, _ := ListHistory(cacheFile)
+	assert.Len(entries, 1, "unchanged rules are not saved again")
+	first := entries[0].ID
+
+	server.body = "com\nexample\ncorp.example\n"
+	assert.Nil(tld.Refresh(), "refresh")
+	server.body = "com\nexample\ncorp.example\nteam.corp.example\n"
+	assert.Nil(tld.Refresh(), "refresh")
+
+	entries, err = ListHistory(cacheFile)
+	assert.Nil(err, "Error nil")
+	if !assert.Len(entries, 2, "oldest version removed") {
+		return
+	}
+	assert.NotEqual(first, entries[1].ID, "oldest version removed")
+	assert.True(entries[0].HasHeader, "header")
+	assert.Equal(4, entries[0].Header.Rules, "newest first")
+	assert.Equal(3, entries[1].Header.Rules, "previous version")
+	assert.True(entries[0].Saved.After(entries[1].Saved), "save time")
+	assertResult(t, "wiki.team.corp.example", &Result{Flag: Domain, SubDomain: "", Domain: "wiki", Tld: "team.corp.example"}, tld.Extract("wiki.team.corp.example"), "latest list")
+
+	assert.Nil(tld.Rollback(entries[1].ID), "rollback")
+	assertResult(t, "wiki.team.corp.example", &Result{Flag: Domain, SubDomain: "wiki", Domain: "team", Tld: "corp.example"}, tld.Extract("wiki.team.corp.example"), "rolled back list")
+	assert.Nil(tld.Refresh(), "refresh while pinned")
+	assertResult(t, "wiki.team.corp.example", &Result{Flag: Domain, SubDomain: "wiki", Domain: "team", Tld: "corp.example"}, tld.Extract("wiki.team.corp.example"), "pinned across refresh")
+	assert.Equal(entries[1].Saved, tld.LoadedAt(), "loaded at save time")
+	assert.NotNil(tld.Rollback("20200101T000000.000000000Z-000000000000"), "unknown version")
+
+	assert.Nil(tld.Unpin(), "unpin")
+	assert.Nil(tld.Refresh(), "refresh after unpin")
+	assertResult(t, "wiki.team.corp.example", &Result{Flag: Domain, SubDomain: "", Domain: "wiki", Tld: "team.corp.example"}, tld.Extract("wiki.team.corp.example"), "latest list again")
+
+	pinned, err := NewWithOptions(WithCacheFile(cacheFile), WithPinnedVersion(entries[1].ID))
+	assert.Nil(err, "Error nil")
+	assertResult(t, "wiki.team.corp.example", &Result{Flag: Domain, SubDomain: "wiki", Domain: "team", Tld: "corp.example"}, pinned.Extract("wiki.team.corp.example"), "pinned extractor")
+	_, err = NewWithOptions(WithCacheFile(cacheFile), WithPinnedVersion("missing"))
+	assert.NotNil(err, "no embedded fallback for a missing pinned version")
+
+	assert.Nil(RollbackCache(cacheFile, entries[1].ID), "roll back cache file")
+	rolledBack, _ := ReadFile(cacheFile)
+	saved, _ := ReadFile(entries[1].Path)
+	assert.Equal(saved, rolledBack, "cache file replaced")
+
+	server.etag = `"latest"`
+	assert.Nil(tld.Refresh(), "refresh storing an ETag")
+	assert.Nil(RollbackCache(cacheFile, entries[1].ID), "roll back cache file")
+	_, err = os.Stat(cacheFile + CacheMetaSuffix)
+	assert.True(os.IsNotExist(err), "metadata of the newer list removed")
+	refreshed, err := NewWithOptions(
+		WithCacheFile(cacheFile),
+		WithURLs(server.URL),
+		WithHTTPClient(server.Client()),
+		WithRefreshPolicy(RefreshAlways),
+		WithEmbeddedFallback(false),
+	)
+	assert.Nil(err, "Error nil")
+	assertResult(t, "wiki.team.corp.example", &Result{Flag: Domain, SubDomain: "", Domain: "wiki", Tld: "team.corp.example"}, refreshed.Extract("wiki.team.corp.example"), "refresh after rollback downloads the latest list")
+}
+
+func Test_ListHistory_missing(t *testing.T) {
+	assert := assert.New(t)
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+
+	entries, err := ListHistory(cacheFile)
+	assert.Nil(err, "no history is not an error")
+	assert.Len(entries, 0, "no versions")
+
+	assert.Nil(os.MkdirAll(cacheFile+HistorySuffix, 0755), "history dir")
+	assert.Nil(WriteFile(filepath.Join(cacheFile+HistorySuffix, "notes.txt"), []byte("x")), "stray file")
+	entries, _ = ListHistory(cacheFile)
+	assert.Len(entries, 0, "stray files ignored")
+	assert.NotNil(RollbackCache(cacheFile, "missing"), "unknown version")
+}
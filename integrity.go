@@ -0,0 +1,168 @@
+package tldextract
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// SignatureSuffix - appended to a list URL or cache file name for its detached Ed25519 signature
+const SignatureSuffix = ".sig"
+
+// Verifier - integrity requirements for list data, a list must pass every configured check.
+// The zero value verifies nothing.
+type Verifier struct {
+	// SHA256 holds the hex digests a list may have
+	SHA256 []string
+	// PublicKey verifies the detached signature stored next to the list
+	PublicKey ed25519.PublicKey
+}
+
+// IntegrityError - list data from Source failed verification
+type IntegrityError struct {
+	Source string
+	Detail string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("list from '%s' failed integrity verification: %s", e.Source, e.Detail)
+}
+
+// enabled - report whether any check is configured
+func (v Verifier) enabled() bool {
+	return len(v.SHA256) > 0 || v.PublicKey != nil
+}
+
+// Verify - check data from source against the pinned digests and signature, which may be
+// raw or base64 encoded
+func (v Verifier) Verify(source string, data []byte, signature []byte) error {
+	if len(v.SHA256) > 0 {
+		sum := sha256.Sum256(data)
+		digest := hex.EncodeToString(sum[:])
+		pinned := false
+		for _, pin := range v.SHA256 {
+			if strings.EqualFold(strings.TrimSpace(pin), digest) {
+				pinned = true
+				break
+			}
+		}
+		if !pinned {
+			return &IntegrityError{Source: source, Detail: "sha256 " + digest + " is not pinned"}
+		}
+	}
+	if v.PublicKey != nil {
+		if len(v.PublicKey) != ed25519.PublicKeySize {
+			return &IntegrityError{Source: source, Detail: fmt.Sprintf("ed25519 public key has %d bytes", len(v.PublicKey))}
+		}
+		sig, err := decodeSignature(signature)
+		if err != nil {
+			return &IntegrityError{Source: source, Detail: err.Error()}
+		}
+		if !ed25519.Verify(v.PublicKey, data, sig) {
+			return &IntegrityError{Source: source, Detail: "ed25519 signature does not match"}
+		}
+	}
+	return nil
+}
+
+// SignCacheFile - write the detached Ed25519 signature of fqdn, decompressed, to fqdn + SignatureSuffix
+func SignCacheFile(fqdn string, key ed25519.PrivateKey) error {
+	data, err := ReadFile(fqdn)
+	if err == nil {
+		data, err = Decompress(data)
+	}
+	if err != nil {
+		return err
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+	return WriteFile(fqdn+SignatureSuffix, []byte(signature+"\n"))
+}
+
+func decodeSignature(signature []byte) ([]byte, error) {
+	if len(signature) == ed25519.SignatureSize {
+		return signature, nil
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("malformed ed25519 signature")
+	}
+	return sig, nil
+}
+
+// readVerifiedFile - read and decompress fqdn and verify it with the signature file next to it
+func readVerifiedFile(fqdn string, verifier Verifier) ([]byte, error) {
+	data, err := ReadFile(fqdn)
+	if err == nil {
+		data, err = Decompress(data)
+	}
+	if err != nil || !verifier.enabled() {
+		return data, err
+	}
+	var signature []byte
+	if verifier.PublicKey != nil {
+		if signature, err = ReadFile(fqdn + SignatureSuffix); err != nil {
+			return nil, &IntegrityError{Source: fqdn, Detail: err.Error()}
+		}
+	}
+	return data, verifier.Verify(fqdn, data, signature)
+}
+
+// createVerifiedCacheFile - download the single list URL and its signature, and store them verbatim in
+// the cache file so it can be verified when it is loaded again.
+func (o *options) createVerifiedCacheFile(ctx context.Context, cached *RuleSet, meta CacheMeta) (*RuleSet, error) {
+	client, fqdn, urls, v, verifier := o.client, o.cacheFile, o.urls, o.listValidation(), o.verifier
+	if len(urls) != 1 {
+		return nil, fmt.Errorf("integrity verification needs a single list url, got %d", len(urls))
+	}
+	url := urls[0]
+	data, sourceMeta, notModified, err := fetchList(ctx, client, url, meta.Sources[url])
+	if err == nil && notModified {
+		if cached != nil {
+			return cached, touchCacheFile(fqdn)
+		}
+		data, sourceMeta, _, err = fetchList(ctx, client, url, SourceMeta{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := v.ValidateSource(url, string(data)); err != nil {
+		return nil, err
+	}
+	var signature []byte
+	if verifier.PublicKey != nil {
+		if signature, _, _, err = fetch(ctx, client, url+SignatureSuffix, SourceMeta{}); err != nil {
+			return nil, &IntegrityError{Source: url, Detail: err.Error()}
+		}
+	}
+	if err := verifier.Verify(url, data, signature); err != nil {
+		return nil, err
+	}
+
+	uList := ParseRules(url, string(data))
+	if err := v.ValidateList(uList, cached.Len()); err != nil {
+		return nil, err
+	}
+	if fqdn == "" {
+		return uList, nil
+	}
+	if signature != nil {
+		if err := WriteFile(fqdn+SignatureSuffix, signature); err != nil {
+			return uList, err
+		}
+	}
+	compressed, err := Compress(data, o.compression)
+	if err != nil {
+		return uList, err
+	}
+	if err := WriteFile(fqdn, compressed); err != nil {
+		return uList, err
+	}
+	if err := saveHistory(fqdn, compressed, signature, o.history); err != nil {
+		o.logf("tldextract: saving cache file version failed: %v", err)
+	}
+	return uList, WriteCacheMeta(fqdn, CacheMeta{Sources: map[string]SourceMeta{url: sourceMeta}})
+}
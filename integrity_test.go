@@ -0,0 +1,187 @@
+package tldextract
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testDigest(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func newSignedListServer(t *testing.T, body string, signature []byte) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, SignatureSuffix) {
+			w.Write(signature)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func Test_Verifier_Verify(t *testing.T) {
+	assert := assert.New(t)
+	public, private, _ := ed25519.GenerateKey(nil)
+	other, _, _ := ed25519.GenerateKey(nil)
+	data := []byte(testListBody)
+	signature := ed25519.Sign(private, data)
+	tests := []struct {
+		name      string
+		verifier  Verifier
+		signature []byte
+		valid     bool
+	}{
+		{name: "zero value", verifier: Verifier{}, valid: true},
+		{name: "pinned", verifier: Verifier{SHA256: []string{"00", strings.ToUpper(testDigest(testListBody))}}, valid: true},
+		{name: "not pinned", verifier: Verifier{SHA256: []string{testDigest("com\n")}}, valid: false},
+		{name: "raw signature", verifier: Verifier{PublicKey: public}, signature: signature, valid: true},
+		{name: "other key", verifier: Verifier{PublicKey: other}, signature: signature, valid: false},
+		{name: "missing signature", verifier: Verifier{PublicKey: public}, valid: false},
+		{name: "short key", verifier: Verifier{PublicKey: public[:16]}, signature: signature, valid: false},
+	}
+	for _, test := range tests {
+		err := test.verifier.Verify("http://list", data, test.signature)
+		if test.valid {
+			assert.Nil(err, test.name)
+			continue
+		}
+		var iErr *IntegrityError
+		if assert.True(errors.As(err, &iErr), test.name) {
+			assert.Equal("http://list", iErr.Source, test.name)
+		}
+	}
+}
+
+func Test_NewWithOptions_sha256(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := newListServer(t, testListBody)
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+
+	tld, err := NewWithOptions(
+		WithCacheFile(cacheFile),
+		WithURLs(server.URL),
+		WithHTTPClient(server.Client()),
+		WithSHA256(testDigest(testListBody)),
+		WithEmbeddedFallback(false),
+	)
+	assert.Nil(err, "pinned list")
+	assertResult(t, "foo.blogspot.com", &Result{Flag: Domain, Domain: "foo", Tld: "blogspot.com", IsPrivate: true}, tld.Extract("foo.blogspot.com"), "pinned list")
+	data, _ := ReadFile(cacheFile)
+	assert.Equal(testListBody, string(data), "cached verbatim")
+
+	_, err = NewWithOptions(
+		WithCacheFile(cacheFile),
+		WithRefreshPolicy(RefreshNever),
+		WithSHA256(testDigest("com\n")),
+		WithEmbeddedFallback(false),
+	)
+	var iErr *IntegrityError
+	if assert.True(errors.As(err, &iErr), "cache file not pinned") {
+		assert.Equal(cacheFile, iErr.Source, "source")
+	}
+
+	_, err = NewWithOptions(
+		WithURLs(server.URL),
+		WithHTTPClient(server.Client()),
+		WithSHA256(testDigest("com\n")),
+		WithEmbeddedFallback(false),
+	)
+	assert.True(errors.As(err, &iErr), "download not pinned")
+}
+
+func Test_NewWithOptions_public_key(t *testing.T) {
+	assert := assert.New(t)
+	public, private, _ := ed25519.GenerateKey(nil)
+	signed := filepath.Join(t.TempDir(), "signed.cache")
+	assert.Nil(WriteFile(signed, []byte(testListBody)), "write list")
+	assert.Nil(SignCacheFile(signed, private), "sign list")
+	signature, _ := ReadFile(signed + SignatureSuffix)
+	server := newSignedListServer(t, testListBody, signature)
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+
+	_, err := NewWithOptions(
+		WithCacheFile(cacheFile),
+		WithURLs(server.URL+"/list.dat"),
+		WithHTTPClient(server.Client()),
+		WithPublicKey(public),
+		WithEmbeddedFallback(false),
+	)
+	assert.Nil(err, "signed list")
+	stored, _ := ReadFile(cacheFile + SignatureSuffix)
+	assert.Equal(signature, stored, "signature stored next to the cache file")
+
+	tld, err := NewWithOptions(
+		WithCacheFile(cacheFile),
+		WithRefreshPolicy(RefreshNever),
+		WithPublicKey(public),
+		WithEmbeddedFallback(false),
+	)
+	assert.Nil(err, "signed cache file")
+	assertResult(t, "foo.blogspot.com", &Result{Flag: Domain, Domain: "foo", Tld: "blogspot.com", IsPrivate: true}, tld.Extract("foo.blogspot.com"), "signed cache file")
+
+	assert.Nil(WriteFile(cacheFile, []byte(testListBody+"evil.example\n")), "tamper with cache file")
+	_, err = NewWithOptions(
+		WithCacheFile(cacheFile),
+		WithRefreshPolicy(RefreshNever),
+		WithPublicKey(public),
+		WithEmbeddedFallback(false),
+	)
+	var iErr *IntegrityError
+	assert.True(errors.As(err, &iErr), "tampered cache file")
+
+	tampered := newSignedListServer(t, testListBody+"evil.example\n", signature)
+	_, err = NewWithOptions(
+		WithURLs(tampered.URL+"/list.dat"),
+		WithHTTPClient(tampered.Client()),
+		WithPublicKey(public),
+		WithEmbeddedFallback(false),
+	)
+	assert.True(errors.As(err, &iErr), "tampered download")
+}
+
+func Test_NewWithOptions_verification_single_url(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := newListServer(t, testListBody)
+
+	_, err := NewWithOptions(
+		WithURLs(server.URL, server.URL),
+		WithHTTPClient(server.Client()),
+		WithSHA256(testDigest(testListBody)),
+		WithEmbeddedFallback(false),
+	)
+	assert.NotNil(err, "several urls")
+}
+
+func Test_NewWithOptions_verification_without_fallback(t *testing.T) {
+	assert := assert.New(t)
+	public, _, _ := ed25519.GenerateKey(nil)
+	server := newSignedListServer(t, testListBody, []byte("not a signature"))
+
+	_, err := NewWithOptions(
+		WithURLs(server.URL+"/list.dat"),
+		WithHTTPClient(server.Client()),
+		WithPublicKey(public),
+	)
+	var iErr *IntegrityError
+	assert.True(errors.As(err, &iErr), "bad signature not replaced by the embedded snapshot")
+
+	_, err = NewWithOptions(
+		WithURLs(server.URL+"/list.dat"),
+		WithHTTPClient(server.Client()),
+		WithSHA256(testDigest("com\n")),
+	)
+	assert.True(errors.As(err, &iErr), "unpinned list not replaced by the embedded snapshot")
+}
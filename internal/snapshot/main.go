@@ -0,0 +1,45 @@
+// Command snapshot copies a local public suffix list file over the snapshot embedded in tldextract.
+//
+// The source file defaults to $TLDEXTRACT_SNAPSHOT_SOURCE, then public_suffix_list.dat in the
+// current directory. Run it through "go generate" from the repository root.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mjd2021usa/tldextract"
+)
+
+func main() {
+	defaultSource := tldextract.GetEnvString("TLDEXTRACT_SNAPSHOT_SOURCE", "public_suffix_list.dat")
+	in := flag.String("in", defaultSource, "local public suffix list file")
+	out := flag.String("out", "data/public_suffix_list.dat", "embedded snapshot file to overwrite")
+	flag.Parse()
+
+	if err := refresh(*in, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "snapshot: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func refresh(in string, out string) error {
+	data, err := tldextract.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	buffer := string(data)
+	for _, marker := range []string{tldextract.ICANNBeginMarker, tldextract.PrivateBeginMarker} {
+		if !strings.Contains(buffer, marker) {
+			return fmt.Errorf("'%s' is missing section marker %s", in, marker)
+		}
+	}
+	uList := tldextract.ParseRules(in, buffer)
+	if err := tldextract.WriteFile(out, data); err != nil {
+		return err
+	}
+	fmt.Printf("snapshot: wrote %d rules from '%s' to '%s'\n", uList.Len(), in, out)
+	return nil
+}
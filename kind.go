@@ -0,0 +1,99 @@
+package tldextract
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Kind - what Extract found in its input. The values are stable, 3 to 6 match the untyped Flag
+// constants of earlier releases.
+type Kind int
+
+const (
+	// Malformed - input that is not a valid host, e.g. a broken IP literal or a bare public suffix
+	Malformed Kind = 3
+	// Domain - host under a public suffix
+	Domain Kind = 4
+	// IPv4 - IPv4 address, in Domain
+	IPv4 Kind = 5
+	// IPv6 - IPv6 address in canonical form, in Domain
+	IPv6 Kind = 6
+	// NoHost - input without a host
+	NoHost Kind = 7
+	// UnknownTLD - host not under any public suffix rule in use
+	UnknownTLD Kind = 8
+	// InvalidLabel - host with a label that cannot be part of a domain name
+	InvalidLabel Kind = 9
+)
+
+var kindNames = map[Kind]string{
+	Malformed:    "malformed",
+	Domain:       "domain",
+	IPv4:         "ipv4",
+	IPv6:         "ipv6",
+	NoHost:       "no_host",
+	UnknownTLD:   "unknown_tld",
+	InvalidLabel: "invalid_label",
+}
+
+func (k Kind) String() string {
+	if name, found := kindNames[k]; found {
+		return name
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// ParseKind - kind named name, as returned by String
+func ParseKind(name string) (Kind, error) {
+	for kind, kindName := range kindNames {
+		if kindName == name {
+			return kind, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown kind '%s'", name)
+}
+
+// MarshalText - the kind name, or the number of a kind without one such as the zero value
+func (k Kind) MarshalText() ([]byte, error) {
+	if _, found := kindNames[k]; !found {
+		return []byte(strconv.Itoa(int(k))), nil
+	}
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText - parse a kind name or number
+func (k *Kind) UnmarshalText(text []byte) error {
+	if number, err := strconv.Atoi(string(text)); err == nil {
+		*k = Kind(number)
+		return nil
+	}
+	kind, err := ParseKind(string(text))
+	if err != nil {
+		return err
+	}
+	*k = kind
+	return nil
+}
+
+// MarshalJSON - the kind name as a JSON string, or the number of a kind without one
+func (k Kind) MarshalJSON() ([]byte, error) {
+	if _, found := kindNames[k]; !found {
+		return json.Marshal(int(k))
+	}
+	return json.Marshal(k.String())
+}
+
+// UnmarshalJSON - parse a kind name, or a number as earlier releases logged
+func (k *Kind) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		*k = Kind(number)
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	return k.UnmarshalText([]byte(name))
+}
@@ -0,0 +1,119 @@
+package tldextract
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Kind_String(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		Kind     Kind
+		Expected string
+	}{
+		{Kind: Malformed, Expected: "malformed"},
+		{Kind: Domain, Expected: "domain"},
+		{Kind: IPv4, Expected: "ipv4"},
+		{Kind: IPv6, Expected: "ipv6"},
+		{Kind: NoHost, Expected: "no_host"},
+		{Kind: UnknownTLD, Expected: "unknown_tld"},
+		{Kind: InvalidLabel, Expected: "invalid_label"},
+		{Kind: Kind(0), Expected: "Kind(0)"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(tc.Expected, tc.Kind.String(), tc.Expected)
+		if tc.Kind == 0 {
+			continue
+		}
+		kind, err := ParseKind(tc.Expected)
+		assert.Nil(err, tc.Expected)
+		assert.Equal(tc.Kind, kind, tc.Expected)
+	}
+	_, err := ParseKind("bogus")
+	assert.NotNil(err, "unknown name")
+}
+
+func Test_Kind_stable_values(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]int{3, 4, 5, 6, 7, 8, 9}, []int{int(Malformed), int(Domain), int(IPv4), int(IPv6), int(NoHost), int(UnknownTLD), int(InvalidLabel)})
+}
+
+func Test_Kind_JSON(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := json.Marshal(Result{Flag: UnknownTLD})
+	assert.Nil(err, "Error nil")
+	assert.Contains(string(data), `"Flag":"unknown_tld"`, "kind name")
+
+	var result Result
+	assert.Nil(json.Unmarshal(data, &result), "Error nil")
+	assert.Equal(UnknownTLD, result.Flag, "round trip")
+
+	var kind Kind
+	assert.Nil(json.Unmarshal([]byte(`4`), &kind), "legacy number")
+	assert.Equal(Domain, kind, "legacy number")
+	assert.NotNil(json.Unmarshal([]byte(`"bogus"`), &kind), "unknown name")
+	data, err = json.Marshal(Kind(42))
+	assert.Nil(err, "Error nil")
+	assert.Equal(`42`, string(data), "unknown kind as its number")
+	assert.Nil(json.Unmarshal(data, &kind), "unknown number")
+	assert.Equal(Kind(42), kind, "unknown number round trip")
+
+	text, err := IPv6.MarshalText()
+	assert.Nil(err, "Error nil")
+	assert.Equal("ipv6", string(text), "text form")
+	data, err = json.Marshal(map[Kind]int{IPv4: 1})
+	assert.Nil(err, "Error nil")
+	assert.Equal(`{"ipv4":1}`, string(data), "map key")
+}
+
+func Test_Kind_zero_value(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := json.Marshal(Result{})
+	assert.Nil(err, "zero Result marshals")
+	assert.Contains(string(data), `"Flag":0`, "zero kind as its number")
+
+	result := Result{Flag: Domain}
+	assert.Nil(json.Unmarshal(data, &result), "Error nil")
+	assert.Equal(Kind(0), result.Flag, "zero kind round trip")
+
+	text, err := Kind(0).MarshalText()
+	assert.Nil(err, "Error nil")
+	var kind Kind = Domain
+	assert.Nil(kind.UnmarshalText(text), "Error nil")
+	assert.Equal(Kind(0), kind, "text round trip")
+}
+
+func Test_Extract_kinds(t *testing.T) {
+	assert := assert.New(t)
+
+	tld, err := NewWithOptions(WithCacheFile("test/tld.cache"), WithRefreshPolicy(RefreshNever))
+	assert.Nil(err, "Error nil")
+
+	testCases := []struct {
+		Url      string
+		Expected Kind
+	}{
+		{Url: "", Expected: NoHost},
+		{Url: "http:///path", Expected: NoHost},
+		{Url: "http://example.cannon-fodder/", Expected: UnknownTLD},
+		{Url: "http://ex_ample.com/", Expected: InvalidLabel},
+		{Url: "http://xn--a.de/", Expected: InvalidLabel},
+		{Url: "http://co.uk/", Expected: Malformed},
+		{Url: "http://10.10.10.256/", Expected: Malformed},
+		{Url: "http://[2001:db8::1/", Expected: Malformed},
+		{Url: "http://[2001:db8::1]/", Expected: IPv6},
+		{Url: "http://10.10.10.1/", Expected: IPv4},
+		{Url: "http://www.example.com/", Expected: Domain},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(tc.Expected, tld.Extract(tc.Url).Flag, tc.Url)
+	}
+}
@@ -0,0 +1,154 @@
+package tldextract
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DenyPrefix - starts a layer line that removes an inherited rule instead of adding one
+const DenyPrefix = "-"
+
+// Layer - rules stacked on the public list and the layers before it. A rule the layer adds replaces
+// an inherited rule with the same text, a deny entry removes an inherited rule, e.g. to treat a
+// PRIVATE section suffix as an ordinary domain.
+type Layer struct {
+	// Name is the source of the layer's Rules and Deny entries
+	Name string
+	// URL and File hold rules in public suffix list format, rules outside the section markers are
+	// PRIVATE and lines starting with DenyPrefix are deny entries
+	URL  string
+	File string
+	// Rules and Deny are added to the entries read from URL and File
+	Rules []string
+	Deny  []string
+}
+
+// layerRules - rules and deny entries of one layer
+type layerRules struct {
+	rules *RuleSet
+	deny  []string
+}
+
+// parseLayer - rules and deny entries of buffer read from source, failing on lines that are not valid rules
+func parseLayer(source string, buffer string) (layerRules, error) {
+	lines := []string{}
+	deny := []string{}
+	for idx, line := range strings.Split(buffer, "\n") {
+		text := strings.TrimSpace(line)
+		if strings.HasPrefix(text, DenyPrefix) {
+			text = strings.TrimSpace(strings.TrimPrefix(text, DenyPrefix))
+			if !IsValidRule(text) {
+				return layerRules{}, &ValidationError{Check: CheckRuleSyntax, Source: source, Detail: fmt.Sprintf("line %d: invalid deny entry %q", idx+1, text)}
+			}
+			deny = append(deny, strings.ToLower(text))
+			// Keep line numbers of the rules after it
+			line = ""
+		}
+		lines = append(lines, line)
+	}
+	buffer = strings.Join(lines, "\n")
+	if err := (Validation{CheckSyntax: true}).ValidateSource(source, buffer); err != nil {
+		return layerRules{}, err
+	}
+	return layerRules{rules: parseRules(source, buffer, SectionPrivate), deny: deny}, nil
+}
+
+// load - read the entries of layer from its URL, file and fields
+func (layer Layer) load(ctx context.Context, o *options) (layerRules, error) {
+	loaded := layerRules{rules: NewRuleSet()}
+	for _, location := range []string{layer.URL, layer.File} {
+		if location == "" {
+			continue
+		}
+		var data []byte
+		var err error
+		if location == layer.URL {
+			data, _, _, err = fetchList(ctx, o.client, location, SourceMeta{})
+		} else if data, err = ReadFile(location); err == nil {
+			data, err = Decompress(data)
+		}
+		if err != nil {
+			return layerRules{}, fmt.Errorf("layer '%s': %w", layer.Name, err)
+		}
+		parsed, err := parseLayer(location, string(data))
+		if err != nil {
+			return layerRules{}, fmt.Errorf("layer '%s': %w", layer.Name, err)
+		}
+		loaded.rules.Merge(parsed.rules)
+		loaded.deny = append(loaded.deny, parsed.deny...)
+	}
+	for idx, text := range layer.Rules {
+		if !IsValidRule(text) {
+			return layerRules{}, fmt.Errorf("layer '%s': invalid rule '%s'", layer.Name, text)
+		}
+		loaded.rules.Add(NewRule(text, SectionPrivate, layer.Name, idx+1))
+	}
+	for idx, text := range layer.Deny {
+		text = strings.TrimSpace(text)
+		if !IsValidRule(text) {
+			return layerRules{}, fmt.Errorf("layer '%s': %w", layer.Name, &ValidationError{Check: CheckRuleSyntax, Source: layer.Name, Detail: fmt.Sprintf("entry %d: invalid deny entry %q", idx+1, text)})
+		}
+		loaded.deny = append(loaded.deny, strings.ToLower(text))
+	}
+	return loaded, nil
+}
+
+// applyLayers - stack the layers on base in order, returning the effective rules and the inherited
+// rules removed by deny entries
+func (o *options) applyLayers(ctx context.Context, base *RuleSet) (*RuleSet, []Rule, error) {
+	if len(o.layers) == 0 {
+		return base, nil, nil
+	}
+	rules := NewRuleSet()
+	rules.Merge(base)
+	denied := make(map[string]Rule)
+	for _, layer := range o.layers {
+		loaded, err := layer.load(ctx, o)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, text := range loaded.deny {
+			if rule, found := rules.Get(text); found {
+				rules.Remove(text)
+				denied[text] = rule
+			}
+		}
+		for _, rule := range loaded.rules.Rules() {
+			rules.Remove(rule.Text)
+			rules.Add(rule)
+			delete(denied, rule.Text)
+		}
+	}
+
+	deniedRules := NewRuleSet()
+	for _, rule := range denied {
+		deniedRules.Add(rule)
+	}
+	return rules, deniedRules.Rules(), nil
+}
+
+// loadRules - load the list as o.load does, then add the WithRules rules and the layers
+func (o *options) loadRules(ctx context.Context) (*RuleSet, []Rule, time.Time, error) {
+	cache, loadedAt, err := o.load(ctx)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	o.mergeRules(cache)
+	rules, denied, err := o.applyLayers(ctx, cache)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	return rules, denied, loadedAt, nil
+}
+
+// Denied - the rules removed by the deny entries of the layers, sorted by text
+func (tlde *TLDExtract) Denied() []Rule {
+	if tlde.state == nil {
+		return []Rule{}
+	}
+	tlde.state.rulesMu.Lock()
+	defer tlde.state.rulesMu.Unlock()
+	return append([]Rule{}, tlde.state.denied...)
+}
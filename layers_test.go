@@ -0,0 +1,77 @@
+package tldextract
+
+import (
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewWithOptions_layers(t *testing.T) {
+	assert := assert.New(t)
+	base, _ := newListServer(t, testListBody+"appspot.com\n")
+	org, orgHits := newListServer(t, "// platform domains\nsites.example\n-blogspot.com\n")
+	overrides := filepath.Join(t.TempDir(), "overrides.dat")
+	assert.Nil(WriteFile(overrides, []byte("- appspot.com\n// ===BEGIN ICANN DOMAINS===\nexample\n// ===END ICANN DOMAINS===\n")), "write layer")
+
+	tld, err := NewWithOptions(
+		WithURLs(base.URL),
+		WithHTTPClient(base.Client()),
+		WithEmbeddedFallback(false),
+		WithLayers(
+			Layer{Name: "org", URL: org.URL},
+			Layer{Name: "billing", File: overrides, Rules: []string{"blogspot.com"}, Deny: []string{"sites.example"}},
+		),
+	)
+	assert.Nil(err, "Error nil")
+
+	actual := tld.Extract("foo.blogspot.com")
+	assertResult(t, "foo.blogspot.com", &Result{Flag: Domain, Domain: "foo", Tld: "blogspot.com"}, actual, "rule denied by one layer and added back by a later one")
+	assert.Equal(Rule{Text: "blogspot.com", Type: RuleNormal, Section: SectionPrivate, Source: "billing", Line: 1}, *actual.Rule, "later layer provenance")
+	assertResult(t, "foo.appspot.com", &Result{Flag: Domain, SubDomain: "foo", Domain: "appspot", Tld: "com"}, tld.Extract("foo.appspot.com"), "denied rule")
+	assertResult(t, "shop.sites.example", &Result{Flag: Domain, SubDomain: "shop", Domain: "sites", Tld: "example"}, tld.Extract("shop.sites.example"), "rule of a lower layer denied")
+	actual = tld.Extract("www.example")
+	assert.Equal(overrides, actual.Rule.Source, "layer rule replaces inherited rule")
+	assert.False(actual.IsPrivate, "section markers in layers")
+
+	denied := tld.Denied()
+	if assert.Len(denied, 2, "denied rules") {
+		assert.Equal(Rule{Text: "appspot.com", Type: RuleNormal, Section: SectionICANN, Source: base.URL, Line: 8}, denied[0], "denied list rule")
+		assert.Equal(org.URL, denied[1].Source, "denied layer rule")
+	}
+
+	assert.Nil(tld.Refresh(), "refresh")
+	assert.Equal(int32(2), atomic.LoadInt32(orgHits), "layers reloaded on refresh")
+	assertResult(t, "foo.appspot.com", &Result{Flag: Domain, SubDomain: "foo", Domain: "appspot", Tld: "com"}, tld.Extract("foo.appspot.com"), "denied after refresh")
+}
+
+func Test_NewWithOptions_layer_errors(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		name  string
+		layer Layer
+	}{
+		{name: "missing file", layer: Layer{Name: "missing", File: filepath.Join(t.TempDir(), "missing.dat")}},
+		{name: "invalid rule", layer: Layer{Name: "inline", Rules: []string{"<html>"}}},
+	}
+	for _, test := range tests {
+		_, err := NewWithOptions(WithCacheFile("test/tld.cache"), WithRefreshPolicy(RefreshNever), WithLayers(test.layer))
+		assert.NotNil(err, test.name)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.dat")
+	assert.Nil(WriteFile(bad, []byte("ok.example\n-<html>\n")), "write layer")
+	_, err := NewWithOptions(WithCacheFile("test/tld.cache"), WithRefreshPolicy(RefreshNever), WithLayers(Layer{Name: "bad", File: bad}))
+	var vErr *ValidationError
+	if assert.True(errors.As(err, &vErr), "invalid deny entry") {
+		assert.Equal(CheckRuleSyntax, vErr.Check, "check")
+	}
+
+	_, err = NewWithOptions(WithCacheFile("test/tld.cache"), WithRefreshPolicy(RefreshNever), WithLayers(Layer{Name: "typo", Deny: []string{"blogspot,com"}}))
+	if assert.True(errors.As(err, &vErr), "invalid inline deny entry") {
+		assert.Equal(CheckRuleSyntax, vErr.Check, "check")
+		assert.Equal("typo", vErr.Source, "source")
+	}
+}
@@ -0,0 +1,376 @@
+package tldextract
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// RefreshPolicy - when the list is downloaded instead of read from the cache file
+type RefreshPolicy int
+
+const (
+	// RefreshAlways downloads the list, falling back to the cache file
+	RefreshAlways RefreshPolicy = iota
+	// RefreshIfStale reads the cache file unless it is older than the max age or missing
+	RefreshIfStale
+	// RefreshNever reads the cache file only and never touches the network
+	RefreshNever
+)
+
+// DefaultCacheMaxAge - seconds a cache file is used before RefreshIfStale downloads the list again
+const DefaultCacheMaxAge int64 = 24 * 60 * 60
+
+// Logger - receives load and fallback messages, *log.Logger satisfies it
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Option - configures NewWithOptions
+type Option func(*options)
+
+type options struct {
+	cacheFile        string
+	urls             []string
+	client           *http.Client
+	transport        http.RoundTripper
+	refresh          RefreshPolicy
+	maxAge           time.Duration
+	timeout          int64
+	logger           Logger
+	embeddedFallback bool
+	privateRules     bool
+	rules            []string
+	debug            bool
+	autoRefresh      time.Duration
+	validation       Validation
+	validationSet    bool
+	verifier         Verifier
+	layers           []Layer
+	sources          []RuleSource
+	compression      Compression
+	history          int
+	pinned           string
+	whatwg           bool
+}
+
+func defaultOptions() *options {
+	timeout := GetEnvInt64("TLDEXTRACT_CACHE_TIMEOUT", 10, 64, DefaultCacheTimeout)
+	maxAge := GetEnvInt64("TLDEXTRACT_CACHE_MAX_AGE", 10, 64, DefaultCacheMaxAge)
+	return &options{
+		urls:             envURLs(),
+		refresh:          RefreshIfStale,
+		maxAge:           time.Duration(maxAge) * time.Second,
+		timeout:          timeout,
+		embeddedFallback: true,
+		privateRules:     true,
+		validation:       DefaultValidation,
+	}
+}
+
+// WithCacheFile - read and write the downloaded list at fqdn, no cache file is used when empty
+func WithCacheFile(fqdn string) Option {
+	return func(o *options) {
+		o.cacheFile = fqdn
+	}
+}
+
+// WithURLs - download the list from urls instead of DefaultTldUrls
+func WithURLs(urls ...string) Option {
+	return func(o *options) {
+		o.urls = urls
+	}
+}
+
+// WithHTTPClient - use client for list downloads, its own timeout applies instead of WithTimeout
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithTransport - send list downloads through transport, ignored when WithHTTPClient is given
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithRefreshPolicy - choose when the list is downloaded, RefreshIfStale by default
+func WithRefreshPolicy(policy RefreshPolicy) Option {
+	return func(o *options) {
+		o.refresh = policy
+	}
+}
+
+// WithMaxAge - age after which RefreshIfStale treats the cache file as stale, zero never expires it.
+// Defaults to TLDEXTRACT_CACHE_MAX_AGE seconds, else DefaultCacheMaxAge.
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(o *options) {
+		o.maxAge = maxAge
+	}
+}
+
+// WithTimeout - HTTP timeout in seconds for list downloads
+func WithTimeout(timeout int64) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithLogger - report load and fallback messages to logger
+func WithLogger(logger Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithEmbeddedFallback - use the embedded snapshot when no list could be downloaded or read, enabled by default.
+// It does not apply with WithSHA256 or WithPublicKey, loading fails instead.
+func WithEmbeddedFallback(enabled bool) Option {
+	return func(o *options) {
+		o.embeddedFallback = enabled
+	}
+}
+
+// WithPrivateRules - apply PRIVATE section rules, enabled by default; see TLDExtract.IgnorePrivate
+func WithPrivateRules(enabled bool) Option {
+	return func(o *options) {
+		o.privateRules = enabled
+	}
+}
+
+// WithRules - add custom rules in public suffix list syntax on top of the loaded list, in the PRIVATE section
+func WithRules(rules ...string) Option {
+	return func(o *options) {
+		o.rules = append(o.rules, rules...)
+	}
+}
+
+// WithDebug - print each normalized host while extracting
+func WithDebug(debug bool) Option {
+	return func(o *options) {
+		o.debug = debug
+	}
+}
+
+// WithWHATWG - find the host of the input as a browser does, following the WHATWG URL Standard
+// instead of the lenient parsing of earlier releases. Input without a scheme, including a host and
+// port such as "localhost:8080", is read as http.
+func WithWHATWG(enabled bool) Option {
+	return func(o *options) {
+		o.whatwg = enabled
+	}
+}
+
+// resolveClient - pick the download client: WithHTTPClient, then WithTransport, then a default one
+func (o *options) resolveClient() {
+	if o.client != nil {
+		return
+	}
+	if o.transport != nil {
+		o.client = &http.Client{
+			Timeout:   time.Duration(o.timeout) * time.Second,
+			Transport: o.transport,
+		}
+		return
+	}
+	o.client = newHTTPClient(o.timeout)
+}
+
+// WithValidation - checks a downloaded list must pass before it is used. Defaults to StrictValidation
+// for DefaultTldUrls, the public suffix list itself, and DefaultValidation for other URLs.
+func WithValidation(v Validation) Option {
+	return func(o *options) {
+		o.validation = v
+		o.validationSet = true
+	}
+}
+
+// WithSHA256 - only accept a downloaded list or cache file whose SHA-256 digest is one of the hex digests.
+// Verified lists are cached verbatim, so a single list URL is required.
+func WithSHA256(digests ...string) Option {
+	return func(o *options) {
+		o.verifier.SHA256 = append(o.verifier.SHA256, digests...)
+	}
+}
+
+// WithPublicKey - only accept a list whose detached signature verifies with key. The signature is downloaded
+// from the list URL + SignatureSuffix and stored next to the cache file, see SignCacheFile.
+func WithPublicKey(key ed25519.PublicKey) Option {
+	return func(o *options) {
+		o.verifier.PublicKey = key
+	}
+}
+
+// WithCompression - compress the cache file written after a download with c, loaders detect the
+// compression by itself
+func WithCompression(c Compression) Option {
+	return func(o *options) {
+		o.compression = c
+	}
+}
+
+// WithHistory - keep the last n versions of the cache file in the directory named after it plus
+// HistorySuffix, see ListHistory and RollbackCache
+func WithHistory(n int) Option {
+	return func(o *options) {
+		o.history = n
+	}
+}
+
+// WithPinnedVersion - use the saved cache file version id instead of downloading, until Unpin
+func WithPinnedVersion(id string) Option {
+	return func(o *options) {
+		o.pinned = id
+	}
+}
+
+// WithSources - load the list from the union of sources, earlier sources winning, instead of the cache
+// file and URLs. Every load and refresh reads all sources, the ones failing validation or the WithSHA256
+// pins are skipped.
+func WithSources(sources ...RuleSource) Option {
+	return func(o *options) {
+		o.sources = append(o.sources, sources...)
+	}
+}
+
+// WithLayers - stack layers on the downloaded list in order, after the WithRules rules. Layers are
+// read again on every refresh, and loading fails when one cannot be read.
+func WithLayers(layers ...Layer) Option {
+	return func(o *options) {
+		o.layers = append(o.layers, layers...)
+	}
+}
+
+// WithAutoRefresh - reload the list every interval in the background until Close, swapping the new
+// rules in without blocking Extract. Pair it with WithMaxAge, RefreshIfStale only downloads stale lists.
+func WithAutoRefresh(interval time.Duration) Option {
+	return func(o *options) {
+		o.autoRefresh = interval
+	}
+}
+
+// load - load the list according to the refresh policy, using the embedded snapshot as last resort.
+// The snapshot is never used in place of a list failing WithSHA256 or WithPublicKey verification.
+// loadedAt is when the list was fetched from its source, zero for the embedded snapshot.
+func (o *options) load(ctx context.Context) (*RuleSet, time.Time, error) {
+	uList, loadedAt, err := o.loadList(ctx)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, time.Time{}, ctxErr
+	}
+	if (err != nil || uList.Len() <= 0) && o.embeddedFallback && o.pinned == "" && !o.verifier.enabled() {
+		// Fall back to embedded snapshot
+		if embedded, embeddedErr := LoadEmbedded(); embeddedErr == nil {
+			o.logf("tldextract: using embedded public suffix list: %v", err)
+			return embedded, time.Time{}, nil
+		}
+	}
+	return uList, loadedAt, err
+}
+
+func (o *options) loadList(ctx context.Context) (*RuleSet, time.Time, error) {
+	if o.pinned != "" {
+		return o.loadPinned(ctx)
+	}
+	if len(o.sources) > 0 {
+		return o.loadSources(ctx)
+	}
+	switch o.refresh {
+	case RefreshNever:
+		if o.cacheFile == "" {
+			return nil, time.Time{}, fmt.Errorf("no cache file configured and refresh is disabled")
+		}
+		return o.loadCacheFile(ctx)
+	case RefreshIfStale:
+		if !o.stale() {
+			uList, loadedAt, err := o.loadCacheFile(ctx)
+			if err == nil && uList.Len() > 0 {
+				return uList, loadedAt, nil
+			}
+		}
+	}
+
+	// Refresh cache from URLs
+	uList, loadedAt, err := o.refreshCacheFile(ctx)
+	if err != nil || uList.Len() <= 0 {
+		o.logf("tldextract: refresh failed: %v", err)
+		if o.cacheFile == "" {
+			return uList, time.Time{}, err
+		}
+		// Fall back to cache file
+		return o.loadCacheFile(ctx)
+	}
+	return uList, loadedAt, err
+}
+
+// refreshCacheFile - download the list into the cache file while holding its lock, so one process
+// downloads at a time. A cache file refreshed by another process during the wait is used as is.
+func (o *options) refreshCacheFile(ctx context.Context) (*RuleSet, time.Time, error) {
+	if o.cacheFile == "" {
+		uList, err := o.createNewCacheFile(ctx)
+		return uList, time.Now(), err
+	}
+
+	requested := time.Now()
+	unlock, err := lockFile(ctx, o.cacheFile+LockSuffix)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer unlock()
+
+	if info, err := os.Stat(o.cacheFile); err == nil {
+		if info.ModTime().After(requested) || (o.refresh == RefreshIfStale && !o.stale()) {
+			uList, loadedAt, err := o.loadCacheFile(ctx)
+			if err == nil && uList.Len() > 0 {
+				return uList, loadedAt, nil
+			}
+		}
+	}
+
+	uList, err := o.createNewCacheFile(ctx)
+	return uList, time.Now(), err
+}
+
+// loadCacheFile - read the cache file, loadedAt is its modification time
+func (o *options) loadCacheFile(ctx context.Context) (*RuleSet, time.Time, error) {
+	uList, err := loadCacheFile(ctx, o.cacheFile, o.verifier)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	info, err := os.Stat(o.cacheFile)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return uList, info.ModTime(), nil
+}
+
+// stale - report whether the cache file is missing or older than maxAge
+func (o *options) stale() bool {
+	if o.cacheFile == "" {
+		return true
+	}
+	info, err := os.Stat(o.cacheFile)
+	if err != nil {
+		return true
+	}
+	return o.maxAge > 0 && time.Since(info.ModTime()) > o.maxAge
+}
+
+// mergeRules - add custom rules to uList without overriding rules already loaded
+func (o *options) mergeRules(uList *RuleSet) {
+	for idx, line := range o.rules {
+		if len(RemoveNoiseLines([]string{line})) > 0 {
+			uList.Add(NewRule(line, SectionPrivate, optionsSource, idx+1))
+		}
+	}
+}
+
+func (o *options) logf(format string, v ...interface{}) {
+	if o.logger != nil {
+		o.logger.Printf(format, v...)
+	}
+}
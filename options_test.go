@@ -0,0 +1,218 @@
+package tldextract
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testListBody = `// ===BEGIN ICANN DOMAINS===
+com
+example
+// ===END ICANN DOMAINS===
+// ===BEGIN PRIVATE DOMAINS===
+blogspot.com
+// ===END PRIVATE DOMAINS===
+`
+
+type testLogger struct {
+	messages []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+func newListServer(t *testing.T, body string) (*httptest.Server, *int32) {
+	hits := new(int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, hits
+}
+
+func Test_NewWithOptions_refresh_always(t *testing.T) {
+	assert := assert.New(t)
+	server, hits := newListServer(t, testListBody)
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+
+	tld, err := NewWithOptions(
+		WithCacheFile(cacheFile),
+		WithURLs(server.URL),
+		WithHTTPClient(server.Client()),
+		WithEmbeddedFallback(false),
+	)
+
+	assert.Nil(err, "Error nil")
+	assert.Equal(int32(1), atomic.LoadInt32(hits), "downloaded once")
+	assertResult(t, "foo.blogspot.com", &Result{Flag: Domain, Domain: "foo", Tld: "blogspot.com"}, tld.Extract("foo.blogspot.com"), "downloaded list")
+	cache, err := LoadCacheFile(cacheFile)
+	assert.Nil(err, "cache file written")
+	assert.Equal(SectionPrivate, ruleSections(cache)["blogspot.com"], "cache file keeps sections")
+}
+
+func Test_NewWithOptions_refresh_if_stale(t *testing.T) {
+	assert := assert.New(t)
+	server, hits := newListServer(t, testListBody)
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+	assert.Nil(WriteFile(cacheFile, []byte("org\n")), "write cache file")
+
+	tld, err := NewWithOptions(
+		WithCacheFile(cacheFile),
+		WithURLs(server.URL),
+		WithHTTPClient(server.Client()),
+		WithRefreshPolicy(RefreshIfStale),
+		WithMaxAge(time.Hour),
+	)
+	assert.Nil(err, "Error nil")
+	assert.Equal(int32(0), atomic.LoadInt32(hits), "fresh cache file is used")
+	assertResult(t, "example.org", &Result{Flag: Domain, Domain: "example", Tld: "org"}, tld.Extract("example.org"), "cached list")
+
+	old := time.Now().Add(-2 * time.Hour)
+	assert.Nil(os.Chtimes(cacheFile, old, old), "age cache file")
+	tld, err = NewWithOptions(
+		WithCacheFile(cacheFile),
+		WithURLs(server.URL),
+		WithHTTPClient(server.Client()),
+		WithRefreshPolicy(RefreshIfStale),
+		WithMaxAge(time.Hour),
+	)
+	assert.Nil(err, "Error nil")
+	assert.Equal(int32(1), atomic.LoadInt32(hits), "stale cache file is refreshed")
+	assertResult(t, "example.com", &Result{Flag: Domain, Domain: "example", Tld: "com"}, tld.Extract("example.com"), "refreshed list")
+}
+
+func Test_NewWithOptions_refresh_never(t *testing.T) {
+	assert := assert.New(t)
+	server, hits := newListServer(t, testListBody)
+	logger := &testLogger{}
+
+	tld, err := NewWithOptions(
+		WithCacheFile("test/tld.cache"),
+		WithURLs(server.URL),
+		WithRefreshPolicy(RefreshNever),
+	)
+	assert.Nil(err, "Error nil")
+	assert.Equal(int32(0), atomic.LoadInt32(hits), "no download")
+	assertResult(t, "godaddy.godaddy", &Result{Flag: Domain, Domain: "godaddy", Tld: "godaddy"}, tld.Extract("godaddy.godaddy"), "cached list")
+
+	tld, err = NewWithOptions(
+		WithCacheFile(filepath.Join(t.TempDir(), "i.do.not.exist.cache")),
+		WithRefreshPolicy(RefreshNever),
+		WithEmbeddedFallback(false),
+	)
+	assert.Nil(tld, "Result nil")
+	assert.NotNil(err, "missing cache file without fallback")
+
+	tld, err = NewWithOptions(
+		WithCacheFile(filepath.Join(t.TempDir(), "i.do.not.exist.cache")),
+		WithRefreshPolicy(RefreshNever),
+		WithLogger(logger),
+	)
+	assert.Nil(err, "Error nil")
+	assertResult(t, "example.co.uk", &Result{Flag: Domain, Domain: "example", Tld: "co.uk"}, tld.Extract("example.co.uk"), "embedded list")
+	assert.Len(logger.messages, 1, "fallback logged")
+}
+
+func Test_NewWithOptions_rules(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := newListServer(t, testListBody)
+
+	tld, err := NewWithOptions(
+		WithURLs(server.URL),
+		WithHTTPClient(server.Client()),
+		WithRules("corp.example", "svc.cluster.local", "// comment", ""),
+		WithPrivateRules(false),
+	)
+	assert.Nil(err, "Error nil")
+	assert.True(tld.IgnorePrivate, "private rules ignored")
+	assertResult(t, "foo.blogspot.com", &Result{Flag: Domain, SubDomain: "foo", Domain: "blogspot", Tld: "com"}, tld.Extract("foo.blogspot.com"), "ICANN only")
+
+	tld, err = NewWithOptions(
+		WithURLs(server.URL),
+		WithHTTPClient(server.Client()),
+		WithRules("corp.example", "svc.cluster.local"),
+	)
+	assert.Nil(err, "Error nil")
+	actual := tld.Extract("wiki.team.corp.example")
+	assertResult(t, "wiki.team.corp.example", &Result{Flag: Domain, SubDomain: "wiki", Domain: "team", Tld: "corp.example"}, actual, "custom rule")
+	assert.True(actual.IsPrivate, "custom rules are private")
+	assertResult(t, "api.ns.svc.cluster.local", &Result{Flag: Domain, SubDomain: "api", Domain: "ns", Tld: "svc.cluster.local"}, tld.Extract("api.ns.svc.cluster.local"), "custom rule not in list")
+}
+
+func Test_NewWithOptions_env_defaults(t *testing.T) {
+	assert := assert.New(t)
+	server, hits := newListServer(t, testListBody)
+	os.Setenv("TLDEXTRACT_URLS", server.URL)
+	defer os.Unsetenv("TLDEXTRACT_URLS")
+
+	tld, err := NewWithOptions(WithEmbeddedFallback(false))
+
+	assert.Nil(err, "Error nil")
+	assert.Equal(int32(1), atomic.LoadInt32(hits), "TLDEXTRACT_URLS honored")
+	assert.Equal(DefaultCacheTimeout, tld.CacheTimeout, "default timeout")
+}
+
+type countingTransport struct {
+	hits      int32
+	userAgent string
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.hits, 1)
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", c.userAgent)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func Test_NewWithOptions_transport(t *testing.T) {
+	assert := assert.New(t)
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		fmt.Fprint(w, testListBody)
+	}))
+	defer server.Close()
+	transport := &countingTransport{userAgent: "tldextract-test"}
+
+	tld, err := NewWithOptions(WithURLs(server.URL), WithTransport(transport), WithEmbeddedFallback(false))
+
+	assert.Nil(err, "Error nil")
+	assert.Equal(int32(1), atomic.LoadInt32(&transport.hits), "transport used")
+	assert.Equal("tldextract-test", userAgent, "transport set User-Agent")
+	assertResult(t, "example.com", &Result{Flag: Domain, Domain: "example", Tld: "com"}, tld.Extract("example.com"), "downloaded list")
+}
+
+func Test_HTTPClient(t *testing.T) {
+	assert := assert.New(t)
+	// TLS server signed by a test CA only its own client trusts
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testListBody)
+	}))
+	defer server.Close()
+
+	_, err := DownloadFile(server.URL, 5)
+	assert.NotNil(err, "default client rejects test CA")
+
+	actual := CreateListWithOptions(WithURLs(server.URL), WithHTTPClient(server.Client()))
+	assert.Equal(SectionPrivate, ruleSections(actual)["blogspot.com"], "client trusting the test CA")
+}
+
+func Test_CreateListWithOptions(t *testing.T) {
+	assert := assert.New(t)
+	server, hits := newListServer(t, testListBody)
+
+	actual := CreateListWithOptions(WithURLs(server.URL, server.URL), WithHTTPClient(server.Client()))
+
+	assert.Equal(int32(2), atomic.LoadInt32(hits), "each URL downloaded")
+	assert.Equal(map[string]Section{"com": SectionICANN, "example": SectionICANN, "blogspot.com": SectionPrivate}, ruleSections(actual), "rules")
+}
@@ -0,0 +1,97 @@
+package tldextract
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoHost - the input has no host, see NoHost
+	ErrNoHost = errors.New("no host")
+	// ErrUnknownSuffix - the host is under no rule in use, see UnknownTLD
+	ErrUnknownSuffix = errors.New("unknown suffix")
+	// ErrInvalidLabel - a label cannot be part of a domain name, see InvalidLabel
+	ErrInvalidLabel = errors.New("invalid label")
+	// ErrMalformed - the input is not a valid host, see Malformed
+	ErrMalformed = errors.New("malformed host")
+)
+
+// ExtractError - Input has no domain for Reason, it unwraps to the sentinel error of Kind
+type ExtractError struct {
+	Input    string
+	Kind     Kind
+	Reason   Reason
+	Label    string
+	Position int
+}
+
+func (e *ExtractError) Error() string {
+	return fmt.Sprintf("'%s': %s", e.Input, e.detail())
+}
+
+// Unwrap - the sentinel error of Kind, for errors.Is
+func (e *ExtractError) Unwrap() error {
+	switch e.Kind {
+	case NoHost:
+		return ErrNoHost
+	case UnknownTLD:
+		return ErrUnknownSuffix
+	case InvalidLabel:
+		return ErrInvalidLabel
+	}
+	return ErrMalformed
+}
+
+func (e *ExtractError) detail() string {
+	switch e.Reason {
+	case ReasonEmptyInput:
+		return "no host"
+	case ReasonHostTooLong:
+		return fmt.Sprintf("host longer than %d characters", MaxHostLength)
+	case ReasonLabelTooLong:
+		return fmt.Sprintf("label '%s' longer than %d characters", e.Label, MaxLabelLength)
+	case ReasonEmptyLabel:
+		return "empty label"
+	case ReasonIllegalCharacter:
+		return fmt.Sprintf("illegal character at position %d in label '%s'", e.Position, e.Label)
+	case ReasonInvalidIDNA:
+		return fmt.Sprintf("label '%s' is not valid IDNA", e.Label)
+	case ReasonInvalidIP:
+		return fmt.Sprintf("invalid IP address '%s'", e.Label)
+	case ReasonUnknownSuffix:
+		return fmt.Sprintf("unknown suffix '%s'", e.Label)
+	case ReasonPublicSuffixOnly:
+		return fmt.Sprintf("'%s' is a public suffix", e.Label)
+	case ReasonInvalidPort:
+		return fmt.Sprintf("invalid port '%s'", e.Label)
+	case ReasonInvalidURL:
+		if e.Label != "" {
+			return fmt.Sprintf("invalid host '%s'", e.Label)
+		}
+		return "invalid URL"
+	}
+	return e.Unwrap().Error()
+}
+
+// Parse - Extract returning an *ExtractError for input without a domain, IPv4 or IPv6 address
+func (tlde *TLDExtract) Parse(url string) (Result, error) {
+	result, err := tlde.ExtractE(url)
+	return *result, err
+}
+
+// ExtractE - Extract returning an *ExtractError for input without a domain, IPv4 or IPv6 address.
+// The result is returned either way.
+func (tlde *TLDExtract) ExtractE(url string) (*Result, error) {
+	result := tlde.Extract(url)
+	switch result.Flag {
+	case Domain, IPv4, IPv6:
+		return result, nil
+	}
+	return result, &ExtractError{
+		Input:    url,
+		Kind:     result.Flag,
+		Reason:   result.Reason,
+		Label:    result.Label,
+		Position: result.Position,
+	}
+}
@@ -0,0 +1,48 @@
+package tldextract
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Parse(t *testing.T) {
+	assert := assert.New(t)
+
+	tld, err := NewWithOptions(WithCacheFile("test/tld.cache"), WithRefreshPolicy(RefreshNever))
+	assert.Nil(err, "Error nil")
+
+	testCases := []struct {
+		Url      string
+		Expected error
+		Message  string
+	}{
+		{Url: "http:///path", Expected: ErrNoHost, Message: "'http:///path': no host"},
+		{Url: "http://example.cannon-fodder/", Expected: ErrUnknownSuffix, Message: "'http://example.cannon-fodder/': unknown suffix 'cannon-fodder'"},
+		{Url: "www.!github.com", Expected: ErrInvalidLabel, Message: "'www.!github.com': illegal character at position 5 in label '!github'"},
+		{Url: "co.uk", Expected: ErrMalformed, Message: "'co.uk': 'co.uk' is a public suffix"},
+		{Url: "10.10.10.256", Expected: ErrMalformed, Message: "'10.10.10.256': invalid IP address '10.10.10.256'"},
+	}
+
+	for _, tc := range testCases {
+		result, err := tld.Parse(tc.Url)
+		assert.True(errors.Is(err, tc.Expected), tc.Url)
+		assert.EqualError(err, tc.Message, tc.Url)
+		var extractErr *ExtractError
+		if assert.True(errors.As(err, &extractErr), tc.Url) {
+			assert.Equal(result.Flag, extractErr.Kind, tc.Url)
+			assert.Equal(result.Reason, extractErr.Reason, tc.Url)
+		}
+	}
+
+	for _, url := range []string{"http://www.example.com/", "10.10.10.1", "[::1]:8080"} {
+		result, err := tld.Parse(url)
+		assert.Nil(err, url)
+		assert.Equal(*tld.Extract(url), result, url)
+	}
+
+	result, err := tld.ExtractE("co.uk")
+	assert.NotNil(err, "error")
+	assert.Equal(ReasonPublicSuffixOnly, result.Reason, "result returned with the error")
+}
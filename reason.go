@@ -0,0 +1,92 @@
+package tldextract
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Reason - why Extract found no domain, empty for Domain, IPv4 and IPv6 results
+type Reason string
+
+const (
+	// ReasonEmptyInput - no host left after removing the scheme, user info, port and path
+	ReasonEmptyInput Reason = "empty_input"
+	// ReasonHostTooLong - host longer than MaxHostLength in ASCII form
+	ReasonHostTooLong Reason = "host_too_long"
+	// ReasonLabelTooLong - label longer than MaxLabelLength in ASCII form
+	ReasonLabelTooLong Reason = "label_too_long"
+	// ReasonEmptyLabel - two dots in a row, or a leading or trailing dot, anywhere in the host
+	ReasonEmptyLabel Reason = "empty_label"
+	// ReasonIllegalCharacter - character not allowed in a domain name, see Result.Position
+	ReasonIllegalCharacter Reason = "illegal_character"
+	// ReasonInvalidIDNA - label UTS #46 cannot map, e.g. broken punycode
+	ReasonInvalidIDNA Reason = "invalid_idna"
+	// ReasonInvalidIP - IP literal that does not parse
+	ReasonInvalidIP Reason = "invalid_ip"
+	// ReasonUnknownSuffix - host under no rule in use
+	ReasonUnknownSuffix Reason = "unknown_suffix"
+	// ReasonPublicSuffixOnly - host that is a public suffix itself, without a domain in front
+	ReasonPublicSuffixOnly Reason = "public_suffix_only"
+	// ReasonInvalidPort - port of an ExtractURL input that is not a number from 0 to 65535
+	ReasonInvalidPort Reason = "invalid_port"
+	// ReasonInvalidURL - input the WHATWG URL Standard rejects, Label is the rejected host if any
+	ReasonInvalidURL Reason = "invalid_url"
+)
+
+const (
+	// MaxHostLength - longest host in ASCII form, RFC 1035 less the root label
+	MaxHostLength = 253
+	// MaxLabelLength - longest label in ASCII form
+	MaxLabelLength = 63
+)
+
+// rejected - result of a host with no domain, label is the offending part of it
+func rejected(kind Kind, reason Reason, label string) *Result {
+	return &Result{Flag: kind, Reason: reason, Label: label}
+}
+
+// checkLengths - reject an ASCII host exceeding the DNS limits or with an empty label, nil when within
+func checkLengths(host string) *Result {
+	if len(host) > MaxHostLength {
+		return rejected(Malformed, ReasonHostTooLong, host)
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" {
+			return rejected(Malformed, ReasonEmptyLabel, label)
+		}
+		if len(label) > MaxLabelLength {
+			return rejected(InvalidLabel, ReasonLabelTooLong, label)
+		}
+	}
+	return nil
+}
+
+// invalidIDNALabel - first label of host failing ToASCII, host itself when no single label fails
+func invalidIDNALabel(host string) string {
+	for _, label := range strings.Split(host, ".") {
+		if _, err := ToASCII(label); err != nil {
+			return label
+		}
+	}
+	return host
+}
+
+// rejectDomain - explain why domain, the label in front of the suffix of host, is not a domain name
+func rejectDomain(host, subDomain, domain string) *Result {
+	if domain == "" {
+		return rejected(Malformed, ReasonPublicSuffixOnly, host)
+	}
+	offset := 0
+	if subDomain != "" {
+		offset = len(subDomain) + 1
+	}
+	for idx, r := range domain {
+		if !domainRegex.MatchString(string(r)) {
+			result := rejected(InvalidLabel, ReasonIllegalCharacter, domain)
+			result.Position = utf8.RuneCountInString(host[:offset+idx]) + 1
+			return result
+		}
+	}
+	// Every character passes, the label is rejected as a whole
+	return rejected(InvalidLabel, ReasonIllegalCharacter, domain)
+}
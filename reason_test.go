@@ -0,0 +1,47 @@
+package tldextract
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Extract_reasons(t *testing.T) {
+	assert := assert.New(t)
+
+	tld, err := NewWithOptions(WithCacheFile("test/tld.cache"), WithRefreshPolicy(RefreshNever))
+	assert.Nil(err, "Error nil")
+
+	longLabel := strings.Repeat("a", 64)
+	longHost := strings.Repeat("abcdefghi.", 26) + "com"
+
+	testCases := []struct {
+		Url         string
+		Expected    Result
+		Description string
+	}{
+		{Url: "http:///path", Expected: Result{Flag: NoHost, Reason: ReasonEmptyInput}, Description: "no host"},
+		{Url: "http://" + longHost + "/", Expected: Result{Flag: Malformed, Reason: ReasonHostTooLong, Label: longHost}, Description: "host too long"},
+		{Url: "http://www." + longLabel + ".com/", Expected: Result{Flag: InvalidLabel, Reason: ReasonLabelTooLong, Label: longLabel}, Description: "label too long"},
+		{Url: "http://www..com/", Expected: Result{Flag: Malformed, Reason: ReasonEmptyLabel}, Description: "empty label"},
+		{Url: "a..example.com", Expected: Result{Flag: Malformed, Reason: ReasonEmptyLabel}, Description: "empty label in the subdomain"},
+		{Url: ".example.com", Expected: Result{Flag: Malformed, Reason: ReasonEmptyLabel}, Description: "leading dot"},
+		{Url: "http://www.!github.com/", Expected: Result{Flag: InvalidLabel, Reason: ReasonIllegalCharacter, Label: "!github", Position: 5}, Description: "illegal character"},
+		{Url: "http://ex_ample.com/", Expected: Result{Flag: InvalidLabel, Reason: ReasonIllegalCharacter, Label: "ex_ample", Position: 3}, Description: "illegal character inside the label"},
+		{Url: "http://www.xn--a.de/", Expected: Result{Flag: InvalidLabel, Reason: ReasonInvalidIDNA, Label: "xn--a"}, Description: "invalid punycode"},
+		{Url: "http://10.10.10.256/", Expected: Result{Flag: Malformed, Reason: ReasonInvalidIP, Label: "10.10.10.256"}, Description: "invalid IPv4"},
+		{Url: "http://[2001:db8::g]/", Expected: Result{Flag: Malformed, Reason: ReasonInvalidIP, Label: "2001:db8::g"}, Description: "invalid IPv6"},
+		{Url: "http://example.cannon-fodder/", Expected: Result{Flag: UnknownTLD, Reason: ReasonUnknownSuffix, Label: "cannon-fodder"}, Description: "unknown suffix"},
+		{Url: "http://co.uk/", Expected: Result{Flag: Malformed, Reason: ReasonPublicSuffixOnly, Label: "co.uk"}, Description: "public suffix only"},
+	}
+
+	for _, tc := range testCases {
+		actual := *tld.Extract(tc.Url)
+		assert.Equal(tc.Expected, actual, tc.Description)
+	}
+
+	actual := tld.Extract("http://www.example.com/")
+	assert.Equal(Domain, actual.Flag, "domain")
+	assert.Equal(Reason(""), actual.Reason, "no reason for a domain")
+}
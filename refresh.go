@@ -0,0 +1,205 @@
+package tldextract
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RefreshStatus - outcome of the refresh attempts made so far, by Refresh or the background refresher
+type RefreshStatus struct {
+	LastAttempt time.Time
+	LastSuccess time.Time
+	LastFailure time.Time
+	// LastError is the error of the last attempt, nil when it succeeded
+	LastError error
+}
+
+// ruleSnapshot - rules, their trie and fetch time, replaced as a whole on refresh
+type ruleSnapshot struct {
+	rules    *RuleSet
+	nodes    *TldNode
+	loadedAt time.Time
+}
+
+func newRuleSnapshot(rules *RuleSet, loadedAt time.Time) *ruleSnapshot {
+	return &ruleSnapshot{rules: rules, nodes: newTldNodes(rules), loadedAt: loadedAt}
+}
+
+// ruleState - rules shared by an extractor and its ICANNOnly copies. Extract loads the current
+// snapshot without locking, refreshes and rule changes build a new trie off to the side and swap it in.
+type ruleState struct {
+	current   atomic.Value // *ruleSnapshot
+	refreshMu sync.Mutex
+
+	// rulesMu guards the loaded list and the runtime changes applied on top of it
+	rulesMu sync.Mutex
+	base    *RuleSet
+	denied  []Rule
+	custom  *RuleSet
+	removed map[string]bool
+
+	statusMu sync.Mutex
+	status   RefreshStatus
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     sync.WaitGroup
+}
+
+func newRuleState(base *RuleSet, loadedAt time.Time) *ruleState {
+	state := &ruleState{stop: make(chan struct{}), base: base, custom: NewRuleSet(), removed: make(map[string]bool)}
+	state.publish(loadedAt)
+	return state
+}
+
+// replaceBase - swap in a newly loaded list, keeping the runtime changes
+func (state *ruleState) replaceBase(base *RuleSet, denied []Rule, loadedAt time.Time) {
+	state.rulesMu.Lock()
+	defer state.rulesMu.Unlock()
+	state.base = base
+	state.denied = denied
+	state.publish(loadedAt)
+}
+
+// publish - swap in the loaded list with the runtime changes applied, the caller holds rulesMu
+// unless state is not shared yet
+func (state *ruleState) publish(loadedAt time.Time) *ruleSnapshot {
+	rules := NewRuleSet()
+	rules.Merge(state.custom)
+	rules.Merge(state.base)
+	for text := range state.removed {
+		rules.Remove(text)
+	}
+	current := newRuleSnapshot(rules, loadedAt)
+	state.current.Store(current)
+	return current
+}
+
+// snapshot - current rules, nil for an extractor built without a constructor
+func (tlde *TLDExtract) snapshot() *ruleSnapshot {
+	if tlde.state == nil {
+		return nil
+	}
+	return tlde.state.snapshot()
+}
+
+func (state *ruleState) snapshot() *ruleSnapshot {
+	return state.current.Load().(*ruleSnapshot)
+}
+
+// nodes - rule trie to match against
+func (tlde *TLDExtract) nodes() *TldNode {
+	if current := tlde.snapshot(); current != nil {
+		return current.nodes
+	}
+	return tlde.TldNodes
+}
+
+// Refresh - reload the list the same way the constructor did and rebuild the rules
+func (tlde *TLDExtract) Refresh() error {
+	return tlde.RefreshContext(context.Background())
+}
+
+// RefreshContext - Refresh that gives up when ctx is done, keeping the current rules on error.
+// Safe to call while other goroutines Extract, concurrent refreshes run one at a time.
+func (tlde *TLDExtract) RefreshContext(ctx context.Context) error {
+	if tlde.options == nil || tlde.state == nil {
+		return fmt.Errorf("no list source configured")
+	}
+	tlde.state.refreshMu.Lock()
+	defer tlde.state.refreshMu.Unlock()
+
+	attempt := time.Now()
+	cache, denied, loadedAt, err := tlde.options.loadRules(ctx)
+	if err == nil {
+		tlde.state.replaceBase(cache, denied, loadedAt)
+	}
+	tlde.state.record(attempt, err)
+	return err
+}
+
+// RefreshStatus - times and error of the refresh attempts made so far
+func (tlde *TLDExtract) RefreshStatus() RefreshStatus {
+	if tlde.state == nil {
+		return RefreshStatus{}
+	}
+	tlde.state.statusMu.Lock()
+	defer tlde.state.statusMu.Unlock()
+	return tlde.state.status
+}
+
+// LoadedAt - when the list in use was fetched from its source: the download time, or the cache
+// file modification time when it was read from disk. Zero for the embedded snapshot.
+func (tlde *TLDExtract) LoadedAt() time.Time {
+	if current := tlde.snapshot(); current != nil {
+		return current.loadedAt
+	}
+	return time.Time{}
+}
+
+// Rules - the rules in use sorted by text, each with the source and line it was read from
+func (tlde *TLDExtract) Rules() []Rule {
+	if current := tlde.snapshot(); current != nil {
+		return current.rules.Rules()
+	}
+	return []Rule{}
+}
+
+// ListAge - time since the list in use was fetched, see LoadedAt
+func (tlde *TLDExtract) ListAge() time.Duration {
+	return time.Since(tlde.LoadedAt())
+}
+
+// Close - stop the background refresher started by WithAutoRefresh, shared with ICANNOnly copies
+func (tlde *TLDExtract) Close() error {
+	if tlde.state == nil {
+		return nil
+	}
+	tlde.state.stopOnce.Do(func() {
+		close(tlde.state.stop)
+	})
+	tlde.state.done.Wait()
+	return nil
+}
+
+func (state *ruleState) record(attempt time.Time, err error) {
+	state.statusMu.Lock()
+	defer state.statusMu.Unlock()
+	state.status.LastAttempt = attempt
+	state.status.LastError = err
+	if err != nil {
+		state.status.LastFailure = attempt
+	} else {
+		state.status.LastSuccess = attempt
+	}
+}
+
+// startAutoRefresh - refresh tlde every interval until Close
+func (state *ruleState) startAutoRefresh(tlde *TLDExtract, interval time.Duration) {
+	// Close abandons an in-flight download
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-state.stop
+		cancel()
+	}()
+
+	state.done.Add(1)
+	go func() {
+		defer state.done.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-state.stop:
+				return
+			case <-ticker.C:
+				if err := tlde.RefreshContext(ctx); err != nil {
+					tlde.options.logf("tldextract: background refresh failed: %v", err)
+				}
+			}
+		}
+	}()
+}
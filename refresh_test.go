@@ -0,0 +1,95 @@
+package tldextract
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AutoRefresh(t *testing.T) {
+	assert := assert.New(t)
+	var body atomic.Value
+	body.Store("com\n")
+	var failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, body.Load().(string))
+	}))
+	defer server.Close()
+
+	tld, err := NewWithOptions(
+		WithURLs(server.URL),
+		WithEmbeddedFallback(false),
+		WithAutoRefresh(20*time.Millisecond),
+	)
+	assert.Nil(err, "Error nil")
+	defer tld.Close()
+	icann := tld.ICANNOnly()
+	assertResult(t, "example.org", &Result{Flag: UnknownTLD}, tld.Extract("example.org"), "rule not loaded yet")
+
+	// Extract keeps running lock free while rules are swapped underneath it
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				tld.Extract("www.example.com")
+			}
+		}
+	}()
+
+	body.Store("com\norg\n")
+	assert.Eventually(func() bool {
+		return tld.Extract("example.org").Flag == Domain
+	}, 2*time.Second, 10*time.Millisecond, "refreshed rules swapped in")
+	assert.Equal(Domain, icann.Extract("example.org").Flag, "ICANNOnly copy shares refreshed rules")
+	status := tld.RefreshStatus()
+	assert.False(status.LastSuccess.IsZero(), "last success recorded")
+	assert.Nil(status.LastError, "no error")
+
+	atomic.StoreInt32(&failing, 1)
+	assert.Eventually(func() bool {
+		return tld.RefreshStatus().LastError != nil
+	}, 2*time.Second, 10*time.Millisecond, "failure recorded")
+	status = tld.RefreshStatus()
+	assert.False(status.LastFailure.IsZero(), "last failure recorded")
+	assertResult(t, "example.org", &Result{Flag: Domain, Domain: "example", Tld: "org"}, tld.Extract("example.org"), "rules kept after failed refresh")
+
+	close(stop)
+	wg.Wait()
+	assert.Nil(tld.Close(), "Close")
+	attempt := tld.RefreshStatus().LastAttempt
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(attempt, tld.RefreshStatus().LastAttempt, "no refresh after Close")
+	assert.Nil(tld.Close(), "Close twice")
+}
+
+func Test_RefreshStatus_manual(t *testing.T) {
+	assert := assert.New(t)
+
+	tld := &TLDExtract{}
+	assert.NotNil(tld.Refresh(), "no list source")
+	assert.Equal(RefreshStatus{}, tld.RefreshStatus(), "no status without constructor")
+	assert.Nil(tld.Close(), "Close without refresher")
+
+	tld, err := NewEmbedded(false)
+	assert.Nil(err, "Error nil")
+	assert.Nil(tld.Refresh(), "Refresh embedded")
+	status := tld.RefreshStatus()
+	assert.False(status.LastSuccess.IsZero(), "success recorded")
+	assert.Nil(status.LastError, "no error")
+}
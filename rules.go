@@ -0,0 +1,190 @@
+package tldextract
+
+import (
+	"sort"
+	"strings"
+)
+
+// RuleType - kind of public suffix list rule
+type RuleType int
+
+const (
+	// RuleNormal - plain rule, e.g. "co.uk"
+	RuleNormal RuleType = iota
+	// RuleWildcard - rule with a "*" label matching any label, e.g. "*.ck"
+	RuleWildcard
+	// RuleException - "!" rule undoing a wildcard, e.g. "!www.ck"
+	RuleException
+)
+
+// Sources of rules that were not read from a list URL or file
+const (
+	embeddedSource = "embedded"
+	optionsSource  = "options"
+	runtimeSource  = "runtime"
+	readerSource   = "reader"
+)
+
+func (t RuleType) String() string {
+	switch t {
+	case RuleWildcard:
+		return "wildcard"
+	case RuleException:
+		return "exception"
+	}
+	return "normal"
+}
+
+// Rule - one public suffix list rule and where it was read from
+type Rule struct {
+	// Text is the lower case rule as written in the list, including "!" and "*"
+	Text    string
+	Type    RuleType
+	Section Section
+	// Source is the URL or file the rule was read from, "embedded" for the compiled in snapshot
+	// and "options" for WithRules
+	Source string
+	// Line is the 1-based line of the rule in Source, the argument position for WithRules
+	Line int
+}
+
+// NewRule - rule from its text as written in a list
+func NewRule(text string, section Section, source string, line int) Rule {
+	text = strings.ToLower(strings.TrimSpace(text))
+	ruleType := RuleNormal
+	switch {
+	case strings.HasPrefix(text, "!"):
+		ruleType = RuleException
+	case strings.Contains(text, "*"):
+		ruleType = RuleWildcard
+	}
+	return Rule{Text: text, Type: ruleType, Section: section, Source: source, Line: line}
+}
+
+// Labels - labels of the rule without the exception marker, from the leftmost
+func (r Rule) Labels() []string {
+	return strings.Split(strings.TrimPrefix(r.Text, "!"), ".")
+}
+
+func (r Rule) String() string {
+	return r.Text
+}
+
+// RuleSet - unique rules keyed by their text, the first rule added wins
+type RuleSet struct {
+	// Version and Commit are the "VERSION" and "COMMIT" comments of the upstream list, when present
+	Version string
+	Commit  string
+
+	rules map[string]Rule
+}
+
+// NewRuleSet - empty rule set
+func NewRuleSet() *RuleSet {
+	return &RuleSet{rules: make(map[string]Rule)}
+}
+
+// ParseRules - normalize buffer read from source into a rule set, each rule keeping the section it was
+// declared in and its line. Rules outside any section marker count as ICANN.
+func ParseRules(source string, buffer string) *RuleSet {
+	return parseRules(source, buffer, SectionICANN)
+}
+
+// parseRules - ParseRules with rules outside any section marker in section outside
+func parseRules(source string, buffer string, outside Section) *RuleSet {
+	rules := NewRuleSet()
+	section := outside
+	for idx, line := range strings.Split(buffer, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "//") {
+			key, value := commentField(line)
+			switch {
+			case key == "version" && rules.Version == "":
+				rules.Version = value
+			case key == "commit" && rules.Commit == "":
+				rules.Commit = value
+			case strings.Contains(line, PrivateBeginMarker):
+				section = SectionPrivate
+			case strings.Contains(line, ICANNBeginMarker):
+				section = SectionICANN
+			case strings.Contains(line, ICANNEndMarker), strings.Contains(line, PrivateEndMarker):
+				section = outside
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		rules.Add(NewRule(line, section, source, idx+1))
+	}
+	return rules
+}
+
+// commentField - lower case key and value of a "// key: value" comment line
+func commentField(line string) (string, string) {
+	field := strings.TrimSpace(strings.TrimPrefix(line, "//"))
+	idx := strings.Index(field, ":")
+	if idx == -1 {
+		return "", ""
+	}
+	return strings.ToLower(strings.TrimSpace(field[:idx])), strings.TrimSpace(field[idx+1:])
+}
+
+// Add - add rule unless a rule with the same text is present, reporting whether it was added
+func (rs *RuleSet) Add(rule Rule) bool {
+	if _, found := rs.rules[rule.Text]; found {
+		return false
+	}
+	rs.rules[rule.Text] = rule
+	return true
+}
+
+// Remove - remove the rule with text, reporting whether it was present
+func (rs *RuleSet) Remove(text string) bool {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if _, found := rs.rules[text]; !found {
+		return false
+	}
+	delete(rs.rules, text)
+	return true
+}
+
+// Merge - add the rules of other that are not present yet
+func (rs *RuleSet) Merge(other *RuleSet) {
+	for _, rule := range other.rules {
+		rs.Add(rule)
+	}
+	if rs.Version == "" && rs.Commit == "" {
+		rs.Version, rs.Commit = other.Version, other.Commit
+	}
+}
+
+// Get - rule with text, e.g. "*.ck"
+func (rs *RuleSet) Get(text string) (Rule, bool) {
+	if rs == nil {
+		return Rule{}, false
+	}
+	rule, found := rs.rules[strings.ToLower(text)]
+	return rule, found
+}
+
+// Len - number of rules, 0 for a nil set
+func (rs *RuleSet) Len() int {
+	if rs == nil {
+		return 0
+	}
+	return len(rs.rules)
+}
+
+// Rules - the rules sorted by text
+func (rs *RuleSet) Rules() []Rule {
+	if rs == nil {
+		return []Rule{}
+	}
+	rules := make([]Rule, 0, len(rs.rules))
+	for _, rule := range rs.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Text < rules[j].Text })
+	return rules
+}
@@ -0,0 +1,92 @@
+package tldextract
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ruleSections - rule texts of rules mapped to their section
+func ruleSections(rules *RuleSet) map[string]Section {
+	sections := make(map[string]Section)
+	for _, rule := range rules.Rules() {
+		sections[rule.Text] = rule.Section
+	}
+	return sections
+}
+
+func Test_NewRule(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		text     string
+		expected Rule
+		labels   []string
+	}{
+		{text: "CO.UK", expected: Rule{Text: "co.uk", Type: RuleNormal}, labels: []string{"co", "uk"}},
+		{text: "*.ck", expected: Rule{Text: "*.ck", Type: RuleWildcard}, labels: []string{"*", "ck"}},
+		{text: " !www.ck ", expected: Rule{Text: "!www.ck", Type: RuleException}, labels: []string{"www", "ck"}},
+	}
+	for _, test := range tests {
+		actual := NewRule(test.text, SectionICANN, "", 0)
+		assert.Equal(test.expected, actual, test.text)
+		assert.Equal(test.labels, actual.Labels(), test.text)
+	}
+}
+
+func Test_ParseRules_provenance(t *testing.T) {
+	assert := assert.New(t)
+
+	rules := ParseRules("http://list", testListBody)
+	rules.Merge(ParseRules("http://other", "com\nnet\n"))
+
+	assert.Equal(4, rules.Len(), "unique rules")
+	rule, found := rules.Get("blogspot.com")
+	assert.True(found, "found")
+	assert.Equal(Rule{Text: "blogspot.com", Type: RuleNormal, Section: SectionPrivate, Source: "http://list", Line: 6}, rule, "private rule")
+	rule, _ = rules.Get("com")
+	assert.Equal("http://list", rule.Source, "first source wins")
+	rule, _ = rules.Get("net")
+	assert.Equal(Rule{Text: "net", Type: RuleNormal, Section: SectionICANN, Source: "http://other", Line: 2}, rule, "merged rule")
+	assert.Equal([]string{"blogspot.com", "com", "example", "net"}, GetKeys(rules), "sorted")
+
+	var empty *RuleSet
+	assert.Equal(0, empty.Len(), "nil set")
+}
+
+func Test_Extract_rule(t *testing.T) {
+	assert := assert.New(t)
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+	assert.Nil(WriteFile(cacheFile, []byte("com\njp\n*.kobe.jp\n!city.kobe.jp\n")), "write cache file")
+
+	tld, err := NewWithOptions(WithCacheFile(cacheFile), WithRefreshPolicy(RefreshNever), WithRules("corp.example"))
+	assert.Nil(err, "Error nil")
+
+	tests := []struct {
+		url      string
+		text     string
+		ruleType RuleType
+		source   string
+	}{
+		{url: "www.google.com", text: "com", ruleType: RuleNormal, source: cacheFile},
+		{url: "a.b.c.kobe.jp", text: "*.kobe.jp", ruleType: RuleWildcard, source: cacheFile},
+		{url: "city.kobe.jp", text: "!city.kobe.jp", ruleType: RuleException, source: cacheFile},
+		{url: "wiki.team.corp.example", text: "corp.example", ruleType: RuleNormal, source: optionsSource},
+	}
+	for _, test := range tests {
+		actual := tld.Extract(test.url)
+		if assert.NotNil(actual.Rule, test.url) {
+			assert.Equal(test.text, actual.Rule.Text, test.url)
+			assert.Equal(test.ruleType, actual.Rule.Type, test.url)
+			assert.Equal(test.source, actual.Rule.Source, test.url)
+			assert.NotZero(actual.Rule.Line, test.url)
+		}
+	}
+	assert.Nil(tld.Extract("localhost").Rule, "no rule")
+
+	found := false
+	for _, rule := range tld.Rules() {
+		found = found || rule.Text == "corp.example"
+	}
+	assert.True(found, "Rules lists custom rules")
+}
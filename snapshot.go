@@ -0,0 +1,22 @@
+package tldextract
+
+import (
+	_ "embed"
+	"fmt"
+)
+
+//go:generate go run ./internal/snapshot -out data/public_suffix_list.dat
+
+// snapshot - public suffix list compiled into the package, refreshed with "go generate"
+//
+//go:embed data/public_suffix_list.dat
+var snapshot []byte
+
+// LoadEmbedded - Normalize the embedded public suffix list snapshot into a rule set
+func LoadEmbedded() (*RuleSet, error) {
+	uList := ParseRules(embeddedSource, string(snapshot))
+	if uList.Len() <= 0 {
+		return nil, fmt.Errorf("embedded public suffix list is empty")
+	}
+	return uList, nil
+}
@@ -0,0 +1,42 @@
+package tldextract
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LoadEmbedded(t *testing.T) {
+	assert := assert.New(t)
+
+	actual, err := LoadEmbedded()
+
+	assert.Nil(err, "Error nil")
+	assert.Equal(SectionICANN, ruleSections(actual)["co.uk"], "ICANN rule")
+	assert.Equal(SectionPrivate, ruleSections(actual)["blogspot.com"], "private rule")
+	assert.Contains(GetKeys(actual), "!city.kawasaki.jp", "exception rule")
+}
+
+func Test_LoadCache_embedded_fallback(t *testing.T) {
+	assert := assert.New(t)
+
+	actual, err := LoadCache(filepath.Join(t.TempDir(), "i.do.not.exist.cache"), []string{}, true, 1)
+
+	assert.Nil(err, "Error nil")
+	assert.Contains(GetKeys(actual), "co.uk", "embedded rules")
+}
+
+func Test_NewEmbedded(t *testing.T) {
+	assert := assert.New(t)
+
+	tld, err := NewEmbedded(false)
+	assert.Nil(err, "Error nil")
+
+	actual := tld.Extract("http://big.long.sub.domain.duckduckgo.co.uk/path")
+	assertResult(t, "co.uk", &Result{Flag: Domain, SubDomain: "big.long.sub.domain", Domain: "duckduckgo", Tld: "co.uk"}, actual, "ICANN suffix")
+
+	actual = tld.Extract("foo.blogspot.com")
+	assertResult(t, "blogspot.com", &Result{Flag: Domain, SubDomain: "", Domain: "foo", Tld: "blogspot.com"}, actual, "private suffix")
+	assert.True(actual.IsPrivate, "private suffix")
+}
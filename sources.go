@@ -0,0 +1,207 @@
+package tldextract
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// RuleSource - a list in public suffix list format, see WithSources
+type RuleSource interface {
+	// Name identifies the source in rule provenance, logs and errors
+	Name() string
+	// Open returns the list, read from the start on every call
+	Open(ctx context.Context) (io.ReadCloser, error)
+}
+
+// FileSource - list in the local file path, e.g. a mounted ConfigMap
+func FileSource(path string) RuleSource {
+	return fileSource{path: path}
+}
+
+type fileSource struct {
+	path string
+}
+
+func (s fileSource) Name() string {
+	return s.path
+}
+
+func (s fileSource) Open(ctx context.Context) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return os.Open(s.path)
+}
+
+// FSSource - list at name in fsys, e.g. an embed.FS
+func FSSource(fsys fs.FS, name string) RuleSource {
+	return fsSource{fsys: fsys, name: name}
+}
+
+type fsSource struct {
+	fsys fs.FS
+	name string
+}
+
+func (s fsSource) Name() string {
+	return s.name
+}
+
+func (s fsSource) Open(ctx context.Context) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.fsys.Open(s.name)
+}
+
+// ReaderSource - list read from r on first use and kept in memory, so refreshes see the same list
+func ReaderSource(name string, r io.Reader) RuleSource {
+	return &ioSource{name: name, r: r}
+}
+
+type ioSource struct {
+	name string
+	once sync.Once
+	r    io.Reader
+	data []byte
+	err  error
+}
+
+func (s *ioSource) Name() string {
+	return s.name
+}
+
+func (s *ioSource) Open(ctx context.Context) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.once.Do(func() {
+		s.data, s.err = ioutil.ReadAll(s.r)
+	})
+	if s.err != nil {
+		return nil, s.err
+	}
+	return ioutil.NopCloser(bytes.NewReader(s.data)), nil
+}
+
+// HTTPSource - list downloaded from url with client, nil for the extractor's client (WithHTTPClient
+// or WithTransport)
+func HTTPSource(url string, client *http.Client) RuleSource {
+	return httpSource{url: url, client: client}
+}
+
+type httpSource struct {
+	url    string
+	client *http.Client
+}
+
+func (s httpSource) Name() string {
+	return s.url
+}
+
+func (s httpSource) Open(ctx context.Context) (io.ReadCloser, error) {
+	client := s.client
+	if client == nil {
+		client = newHTTPClient(DefaultCacheTimeout)
+	}
+	data, _, _, err := fetch(ctx, client, s.url, SourceMeta{})
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// EmbeddedSource - the public suffix list snapshot compiled into the package
+func EmbeddedSource() RuleSource {
+	return embeddedRuleSource{}
+}
+
+type embeddedRuleSource struct{}
+
+func (embeddedRuleSource) Name() string {
+	return embeddedSource
+}
+
+func (embeddedRuleSource) Open(ctx context.Context) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(snapshot)), nil
+}
+
+// LoadSource - read and parse the list of src
+func LoadSource(ctx context.Context, src RuleSource) (*RuleSet, error) {
+	data, err := readSource(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(src.Name(), string(data)), nil
+}
+
+func readSource(ctx context.Context, src RuleSource) ([]byte, error) {
+	rc, err := src.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	return Decompress(data)
+}
+
+// loadSources - merge the lists of the WithSources sources passing validation and verification,
+// skipping sources that fail
+func (o *options) loadSources(ctx context.Context) (*RuleSet, time.Time, error) {
+	if o.verifier.PublicKey != nil {
+		return nil, time.Time{}, fmt.Errorf("signature verification needs a list URL, sources are only checked against WithSHA256")
+	}
+	rules := NewRuleSet()
+	var lastErr error
+	for _, src := range o.sources {
+		if hs, ok := src.(httpSource); ok && hs.client == nil {
+			src = httpSource{url: hs.url, client: o.client}
+		}
+		data, err := readSource(ctx, src)
+		if err == nil {
+			err = o.validation.ValidateSource(src.Name(), string(data))
+		}
+		if err == nil && o.verifier.enabled() {
+			err = o.verifier.Verify(src.Name(), data, nil)
+		}
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, time.Time{}, ctxErr
+			}
+			o.logf("tldextract: skipping source '%s': %v", src.Name(), err)
+			lastErr = err
+			continue
+		}
+		rules.Merge(ParseRules(src.Name(), string(data)))
+	}
+	if rules.Len() <= 0 && lastErr != nil {
+		return nil, time.Time{}, lastErr
+	}
+	if err := o.validation.ValidateList(rules, 0); err != nil {
+		return nil, time.Time{}, err
+	}
+	return rules, time.Now(), nil
+}
+
+// NewWithSources - create TLDExtract from the union of sources, earlier sources winning, with opts
+// applied as NewWithOptions does
+func NewWithSources(sources []RuleSource, opts ...Option) (*TLDExtract, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no rule sources")
+	}
+	return NewWithOptions(append([]Option{WithSources(sources...)}, opts...)...)
+}
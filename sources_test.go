@@ -0,0 +1,81 @@
+package tldextract
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RuleSource_implementations(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := newListServer(t, testListBody)
+	file := filepath.Join(t.TempDir(), "list.dat")
+	assert.Nil(WriteFile(file, []byte(testListBody)), "write list")
+	fsys := fstest.MapFS{"psl/list.dat": &fstest.MapFile{Data: []byte(testListBody)}}
+
+	tests := []struct {
+		src  RuleSource
+		name string
+	}{
+		{src: FileSource(file), name: file},
+		{src: FSSource(fsys, "psl/list.dat"), name: "psl/list.dat"},
+		{src: ReaderSource("fixture", strings.NewReader(testListBody)), name: "fixture"},
+		{src: HTTPSource(server.URL, server.Client()), name: server.URL},
+		{src: EmbeddedSource(), name: embeddedSource},
+	}
+	for _, test := range tests {
+		assert.Equal(test.name, test.src.Name(), test.name)
+		for i := 0; i < 2; i++ {
+			rules, err := LoadSource(context.Background(), test.src)
+			assert.Nil(err, test.name)
+			rule, found := rules.Get("blogspot.com")
+			assert.True(found, test.name)
+			assert.Equal(SectionPrivate, rule.Section, test.name)
+			assert.Equal(test.name, rule.Source, test.name)
+		}
+	}
+
+	_, err := LoadSource(context.Background(), FileSource(filepath.Join(t.TempDir(), "missing.dat")))
+	assert.NotNil(err, "missing file")
+}
+
+func Test_NewWithSources(t *testing.T) {
+	assert := assert.New(t)
+	server, hits := newListServer(t, "<html>captive portal</html>")
+	logger := &testLogger{}
+
+	tld, err := NewWithSources(
+		[]RuleSource{
+			ReaderSource("org", strings.NewReader("corp.example\nblogspot.com\n")),
+			HTTPSource(server.URL, nil),
+			FileSource(filepath.Join("test", "tld.cache")),
+		},
+		WithHTTPClient(server.Client()),
+		WithLogger(logger),
+		WithEmbeddedFallback(false),
+	)
+	assert.Nil(err, "Error nil")
+	assert.Equal(int32(1), atomic.LoadInt32(hits), "HTTP source uses the extractor's client")
+	assert.Len(logger.messages, 1, "failing source logged")
+	assertResult(t, "wiki.team.corp.example", &Result{Flag: Domain, SubDomain: "wiki", Domain: "team", Tld: "corp.example"}, tld.Extract("wiki.team.corp.example"), "reader source")
+	assertResult(t, "www.google.com", &Result{Flag: Domain, SubDomain: "www", Domain: "google", Tld: "com"}, tld.Extract("www.google.com"), "file source")
+	assert.Equal("org", tld.Extract("foo.blogspot.com").Rule.Source, "earlier source wins")
+	assert.Nil(tld.Refresh(), "reader source can be read again")
+
+	_, err = NewWithSources(nil)
+	assert.NotNil(err, "no sources")
+
+	_, err = NewWithSources([]RuleSource{HTTPSource(server.URL, server.Client())}, WithEmbeddedFallback(false))
+	var vErr *ValidationError
+	assert.True(errors.As(err, &vErr), "all sources failed")
+
+	tld, err = NewWithSources([]RuleSource{HTTPSource(server.URL, server.Client())})
+	assert.Nil(err, "embedded fallback")
+	assert.Equal(embeddedSource, tld.Extract("foo.blogspot.com").Rule.Source, "embedded rules")
+}
@@ -1,17 +1,24 @@
 package tldextract
 
 import (
+	"context"
 	"fmt"
 	"io/fs"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+
+	"golang.org/x/net/idna"
 )
 
+// idnaProfile applies UTS #46 lookup mapping; STD3 rules are left to the domain regex
+var idnaProfile = idna.New(idna.MapForLookup(), idna.Transitional(false), idna.StrictDomainName(false))
+
 func SafeParseInt(str string, base int, bitSize int, defaultValue int64) int64 {
 	result, err := strconv.ParseInt(str, base, bitSize)
 	if err != nil {
@@ -49,105 +56,229 @@ func SubDomain(domain string) (string, string) {
 	return strings.Join(splits[0:cnt-1], "."), splits[cnt-1]
 }
 
+// ToASCII - map host through UTS #46 and return its ASCII (punycode) form
+func ToASCII(host string) (string, error) {
+	return idnaProfile.ToASCII(host)
+}
+
+// ToUnicode - return Unicode form of an ASCII host, or host unchanged if it cannot be decoded
+func ToUnicode(host string) string {
+	unicode, err := idnaProfile.ToUnicode(host)
+	if err != nil {
+		return host
+	}
+	return unicode
+}
+
 func IsIPv4(ip net.IP) bool {
 	return ip.To4() != nil
 }
 
 func IsIPv6(ip net.IP) bool {
-	return ip.To16() != nil
+	return ip.To4() == nil && ip.To16() != nil
 }
 
-// CreateList -
-func CreateList(timeout int64) map[string]struct{} {
-	urls := []string{
-		"https://publicsuffix.org/list/public_suffix_list.dat",                              // Main list
-		"https://raw.githubusercontent.com/publicsuffix/list/master/public_suffix_list.dat", // Fallback
-	}
-	uniqueList := make(map[string]struct{})
-	for _, url := range urls {
-		data, err := DownloadFile(url, timeout)
-		if err != nil {
-			continue
-		}
-		resp := NormalizeLines(string(data))
-		for _, item := range resp {
-			uniqueList[item] = struct{}{}
+// NormalizeIPv6 - parse an IPv6 literal with optional "%zone" and return its canonical form
+func NormalizeIPv6(literal string) (string, bool) {
+	addr, zone := literal, ""
+	if idx := strings.IndexByte(literal, '%'); idx != -1 {
+		addr, zone = literal[:idx], literal[idx+1:]
+		if zone == "" {
+			return "", false
 		}
 	}
-	return uniqueList
+	if !strings.Contains(addr, ":") {
+		return "", false
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", false
+	}
+	normalized := ip.String()
+	// net.IP prints IPv4-mapped addresses in dotted form, keep them recognisable as IPv6
+	if ip4 := ip.To4(); ip4 != nil {
+		normalized = "::ffff:" + ip4.String()
+	}
+	if zone != "" {
+		normalized += "%" + zone
+	}
+	return normalized, true
+}
+
+// CreateList - download the TLDEXTRACT_URLS (default DefaultTldUrls) lists into a rule set
+func CreateList(timeout int64) *RuleSet {
+	return downloadUrls2List(context.Background(), newHTTPClient(timeout), envURLs())
+}
+
+// CreateListWithOptions - download the lists configured by opts into a rule set, using their URLs and HTTP client
+func CreateListWithOptions(opts ...Option) *RuleSet {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	o.resolveClient()
+	return downloadUrls2List(context.Background(), o.client, o.urls)
+}
+
+// envURLs - list URLs from TLDEXTRACT_URLS, DefaultTldUrls when unset
+func envURLs() []string {
+	urlsString := GetEnvString("TLDEXTRACT_URLS", strings.Join(DefaultTldUrls, ","))
+	return strings.Split(urlsString, ",")
 }
 
 // CreateNewCacheFile - create new cache file from URLs
-func CreateNewCacheFile(fqdn string, urls []string, timeout int64) (map[string]struct{}, error) {
-	uniqueList := DownloadUrls2List(urls, timeout)
-	keys := GetKeys(uniqueList)
-	if len(keys) > 0 {
-		buf := strings.Join(keys, "\n")
-		err := WriteFile(fqdn, []byte(buf))
-		return uniqueList, err
+func CreateNewCacheFile(fqdn string, urls []string, timeout int64) (*RuleSet, error) {
+	return CreateNewCacheFileContext(context.Background(), fqdn, urls, timeout)
+}
+
+// CreateNewCacheFileContext - create new cache file from URLs, stopping when ctx is done
+func CreateNewCacheFileContext(ctx context.Context, fqdn string, urls []string, timeout int64) (*RuleSet, error) {
+	o := &options{cacheFile: fqdn, urls: urls, client: newHTTPClient(timeout), refresh: RefreshAlways, validation: DefaultValidation}
+	uList, _, err := o.refreshCacheFile(ctx)
+	return uList, err
+}
+
+// createNewCacheFile - download the URLs and write them to the cache file, skipping the write when there is none.
+// An existing cache file is revalidated with the sidecar metadata and kept when no source changed, and
+// is only replaced by a list passing the validation and verifier.
+func (o *options) createNewCacheFile(ctx context.Context) (*RuleSet, error) {
+	fqdn := o.cacheFile
+	meta := CacheMeta{Sources: make(map[string]SourceMeta)}
+	var cached *RuleSet
+	if fqdn != "" {
+		if uList, err := loadCacheFile(ctx, fqdn, o.verifier); err == nil && uList.Len() > 0 {
+			cached = uList
+			meta, _ = LoadCacheMeta(fqdn)
+		}
+	}
+	if o.verifier.enabled() {
+		return o.createVerifiedCacheFile(ctx, cached, meta)
+	}
+
+	validation := o.listValidation()
+	uniqueList, newMeta, notModified, err := downloadSources(ctx, o.client, o.urls, meta, validation)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if notModified && cached != nil {
+		return cached, touchCacheFile(fqdn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := validation.ValidateList(uniqueList, cached.Len()); err != nil {
+		return nil, err
+	}
+	if uniqueList.Len() > 0 {
+		if fqdn == "" {
+			return uniqueList, nil
+		}
+		sources := []string{}
+		for _, url := range o.urls {
+			if _, found := newMeta.Sources[url]; found {
+				sources = append(sources, url)
+			}
+		}
+		buf, err := Compress(CacheFileBytes(uniqueList, time.Now(), sources), o.compression)
+		if err != nil {
+			return uniqueList, err
+		}
+		if err := WriteFile(fqdn, buf); err != nil {
+			return uniqueList, err
+		}
+		if err := saveHistory(fqdn, buf, nil, o.history); err != nil {
+			o.logf("tldextract: saving cache file version failed: %v", err)
+		}
+		return uniqueList, WriteCacheMeta(fqdn, newMeta)
 	}
 	return nil, fmt.Errorf("no records found - skipping overwrite")
 }
 
-// LoadCache - Load cache file with Refresh and fail over options
-func LoadCache(fqdn string, urls []string, refresh bool, timeout int64) (map[string]struct{}, error) {
-	if refresh {
-		// Refresh cache from URLs
-		uList, err := CreateNewCacheFile(fqdn, urls, timeout)
-		if err != nil || len(uList) <= 0 {
-			// Fall back to cache file
-			return LoadCacheFile(fqdn)
-		}
-		return uList, err
+// LoadCache - Load cache file with Refresh and fail over options, using the embedded snapshot as last resort
+func LoadCache(fqdn string, urls []string, refresh bool, timeout int64) (*RuleSet, error) {
+	return LoadCacheContext(context.Background(), fqdn, urls, refresh, timeout)
+}
+
+// LoadCacheContext - LoadCache that stops downloading and reading when ctx is done
+func LoadCacheContext(ctx context.Context, fqdn string, urls []string, refresh bool, timeout int64) (*RuleSet, error) {
+	o := &options{
+		cacheFile:        fqdn,
+		urls:             urls,
+		client:           newHTTPClient(timeout),
+		refresh:          RefreshIfStale,
+		embeddedFallback: true,
+		validation:       DefaultValidation,
 	}
-	uList, err := LoadCacheFile(fqdn)
-	if err != nil || len(uList) <= 0 {
-		// try to refresh cache from URLs
-		return CreateNewCacheFile(fqdn, urls, timeout)
+	if refresh {
+		o.refresh = RefreshAlways
 	}
+	uList, _, err := o.load(ctx)
 	return uList, err
 }
 
-// GetKeys - get list of keys from generic map as string array
-func GetKeys(list map[string]struct{}) []string {
-	keys := make([]string, 0, len(list))
-	for key := range list {
-		keys = append(keys, key)
+// GetKeys - get the sorted rule texts of list as string array
+func GetKeys(list *RuleSet) []string {
+	keys := make([]string, 0, list.Len())
+	for _, rule := range list.Rules() {
+		keys = append(keys, rule.Text)
 	}
 	return keys
 }
 
+// CacheLines - sorted rules of each section wrapped in PSL section markers, ready to write as a cache file
+func CacheLines(list *RuleSet) []string {
+	icann := []string{}
+	private := []string{}
+	for _, rule := range list.Rules() {
+		if rule.Section == SectionPrivate {
+			private = append(private, rule.Text)
+		} else {
+			icann = append(icann, rule.Text)
+		}
+	}
+
+	lines := make([]string, 0, list.Len()+4)
+	lines = append(lines, "// "+ICANNBeginMarker)
+	lines = append(lines, icann...)
+	lines = append(lines, "// "+ICANNEndMarker)
+	lines = append(lines, "// "+PrivateBeginMarker)
+	lines = append(lines, private...)
+	lines = append(lines, "// "+PrivateEndMarker)
+	return lines
+}
+
 // LoadCacheFile - read cache file and Normalize contents (remove comments, split lines, etc)
-func LoadCacheFile(fqdn string) (map[string]struct{}, error) {
-	data, err := ReadFile(fqdn)
-	if err != nil {
+func LoadCacheFile(fqdn string) (*RuleSet, error) {
+	return loadCacheFile(context.Background(), fqdn, Verifier{})
+}
+
+func loadCacheFile(ctx context.Context, fqdn string, verifier Verifier) (*RuleSet, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	// Normalize buffer into string array
-	resp := NormalizeLines(string(data))
-	// Add lines to unique list
-	uList := make(map[string]struct{})
-	for _, item := range resp {
-		uList[item] = struct{}{}
+	data, err := readVerifiedFile(fqdn, verifier)
+	if err != nil {
+		return nil, err
 	}
-	return uList, nil
+	// Normalize buffer and add lines to unique list
+	return ParseRules(fqdn, string(data)), nil
 }
 
-// DownloadUrls2List - Download N number of URLs and merge the unique rows into a generic key map
-func DownloadUrls2List(urls []string, timeout int64) map[string]struct{} {
-	uList := make(map[string]struct{})
-	for _, url := range urls {
-		data, err := DownloadFile(url, timeout)
-		if err != nil {
-			// nothing good, move to next file
-			continue
-		}
-		// Normalize buffer into string array
-		resp := NormalizeLines(string(data))
-		// Add lines to unique list
-		for _, item := range resp {
-			uList[item] = struct{}{}
-		}
+// DownloadUrls2List - Download N number of URLs and merge the unique rows into a rule set
+func DownloadUrls2List(urls []string, timeout int64) *RuleSet {
+	return DownloadUrls2ListContext(context.Background(), urls, timeout)
+}
+
+// DownloadUrls2ListContext - DownloadUrls2List that abandons outstanding downloads when ctx is done
+func DownloadUrls2ListContext(ctx context.Context, urls []string, timeout int64) *RuleSet {
+	return downloadUrls2List(ctx, newHTTPClient(timeout), urls)
+}
+
+func downloadUrls2List(ctx context.Context, client *http.Client, urls []string) *RuleSet {
+	o := &options{urls: urls, validation: DefaultValidation}
+	uList, _, _, _ := downloadSources(ctx, client, urls, CacheMeta{}, o.listValidation())
+	if uList == nil {
+		uList = NewRuleSet()
 	}
 	return uList
 }
@@ -175,27 +306,55 @@ func ReadFile(fqfn string) ([]byte, error) {
 	return ioutil.ReadFile(fqfn)
 }
 
-// WriteFile - Write byte array into file
+// WriteFile - Write byte array into file atomically, readers see either the old or the new content
 func WriteFile(fqfn string, buffer []byte) error {
-	return ioutil.WriteFile(fqfn, buffer, fs.FileMode(0644))
+	dir, name := filepath.Split(fqfn)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := ioutil.TempFile(dir, "."+name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	// Harmless once renamed, cleans up after a failed write
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(buffer); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, fs.FileMode(0644)); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, fqfn)
 }
 
 // DownloadFile - Get body from URL
 func DownloadFile(url string, timeout int64) ([]byte, error) {
-	client := http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
-	}
+	return DownloadFileContext(context.Background(), url, timeout)
+}
 
-	resp, err := client.Get(url)
-	if err != nil {
-		return []byte{}, err
-	}
+// DownloadFileContext - Get body from URL, cancelling the request when ctx is done
+func DownloadFileContext(ctx context.Context, url string, timeout int64) ([]byte, error) {
+	return downloadFile(ctx, newHTTPClient(timeout), url)
+}
 
-	defer resp.Body.Close()
+func downloadFile(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	data, _, _, err := fetch(ctx, client, url, SourceMeta{})
+	return data, err
+}
 
-	if resp.StatusCode == 200 {
-		return ioutil.ReadAll(resp.Body)
+// newHTTPClient - client with timeout in seconds
+func newHTTPClient(timeout int64) *http.Client {
+	return &http.Client{
+		Timeout: time.Duration(timeout) * time.Second,
 	}
-
-	return []byte{}, fmt.Errorf("HTTP Status Code: %d returned", resp.StatusCode)
 }
@@ -1,7 +1,9 @@
 package tldextract
 
 import (
+	"net"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -198,11 +200,106 @@ func Test_SubDomain(t *testing.T) {
 	}
 }
 
+func Test_IsIPv6(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsIPv6(net.ParseIP("2001:db8::1")), "IPv6 address")
+	assert.False(IsIPv6(net.ParseIP("10.10.10.10")), "IPv4 address")
+	assert.False(IsIPv6(nil), "nil address")
+}
+
+func Test_NormalizeIPv6(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		Literal     string
+		Expected    string
+		ExpectedOk  bool
+		Description string
+	}{
+		{
+			Literal:     "2001:0DB8:0000:0000:0000:0000:0000:0001",
+			Expected:    "2001:db8::1",
+			ExpectedOk:  true,
+			Description: "full form",
+		},
+		{
+			Literal:     "::ffff:192.0.2.128",
+			Expected:    "::ffff:192.0.2.128",
+			ExpectedOk:  true,
+			Description: "IPv4-mapped",
+		},
+		{
+			Literal:     "fe80::1%eth0",
+			Expected:    "fe80::1%eth0",
+			ExpectedOk:  true,
+			Description: "zone identifier",
+		},
+		{
+			Literal:     "fe80::1%",
+			Expected:    "",
+			ExpectedOk:  false,
+			Description: "empty zone identifier",
+		},
+		{
+			Literal:     "192.0.2.128",
+			Expected:    "",
+			ExpectedOk:  false,
+			Description: "IPv4 address",
+		},
+		{
+			Literal:     "2001:db8::g",
+			Expected:    "",
+			ExpectedOk:  false,
+			Description: "bad address",
+		},
+	}
+
+	for _, tc := range testCases {
+		actual, ok := NormalizeIPv6(tc.Literal)
+
+		assert.Equal(tc.ExpectedOk, ok, tc.Description)
+		assert.Equal(tc.Expected, actual, tc.Description)
+	}
+}
+
+func Test_ParseRules_sections(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := "com\n// ===BEGIN ICANN DOMAINS===\nCO.UK\n// ===END ICANN DOMAINS===\n\n" +
+		"// ===BEGIN PRIVATE DOMAINS===\n// Blogger\nblogspot.com\ncom\n// ===END PRIVATE DOMAINS===\nlocal\n"
+	uList := ParseRules("test", buffer)
+
+	assert.Equal(map[string]Section{
+		"com":          SectionICANN,
+		"co.uk":        SectionICANN,
+		"blogspot.com": SectionPrivate,
+		"local":        SectionICANN,
+	}, ruleSections(uList), "sections")
+}
+
+func Test_CacheLines_round_trip(t *testing.T) {
+	assert := assert.New(t)
+
+	uList := NewRuleSet()
+	uList.Add(NewRule("com", SectionICANN, "test", 1))
+	uList.Add(NewRule("co.uk", SectionICANN, "test", 2))
+	uList.Add(NewRule("blogspot.com", SectionPrivate, "test", 3))
+	lines := CacheLines(uList)
+	assert.Equal([]string{
+		"// " + ICANNBeginMarker, "co.uk", "com", "// " + ICANNEndMarker,
+		"// " + PrivateBeginMarker, "blogspot.com", "// " + PrivateEndMarker,
+	}, lines, "lines")
+
+	actual := ParseRules("test", strings.Join(lines, "\n"))
+	assert.Equal(ruleSections(uList), ruleSections(actual), "round trip")
+}
+
 func Test_CreateList(t *testing.T) {
 	assert := assert.New(t)
 
 	actual := CreateList(10)
 
 	assert.NotNil(actual, "Not Nil")
-	assert.NotEqual(0, len(actual), "Length is not 0")
+	assert.NotEqual(0, actual.Len(), "Length is not 0")
 }
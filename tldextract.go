@@ -1,10 +1,13 @@
 package tldextract
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -15,11 +18,14 @@ const (
 	// \p{Greek} allows matching a Greek characters
 	DomainRegexText = `^[a-z0-9-\p{Han}-\p{Greek}]{1,63}$`
 	SchemeRegexText = `^([a-z0-9\+\-\.]+:)?//`
+)
 
-	Malformed = iota
-	Domain
-	IPv4
-	IPv6
+// Public suffix list section markers, found in "//" comment lines
+const (
+	ICANNBeginMarker   = "===BEGIN ICANN DOMAINS==="
+	ICANNEndMarker     = "===END ICANN DOMAINS==="
+	PrivateBeginMarker = "===BEGIN PRIVATE DOMAINS==="
+	PrivateEndMarker   = "===END PRIVATE DOMAINS==="
 )
 
 var (
@@ -33,71 +39,180 @@ var (
 	schemeRegex = regexp.MustCompile(SchemeRegexText)
 )
 
+// Section - public suffix list division a rule was declared in
+type Section int
+
+const (
+	SectionICANN Section = iota
+	SectionPrivate
+)
+
 type Result struct {
-	Flag      int
+	Flag      Kind
 	SubDomain string
 	Domain    string
 	Tld       string
+
+	// ASCII (punycode "xn--") forms of SubDomain, Domain and Tld
+	SubDomainASCII string
+	DomainASCII    string
+	TldASCII       string
+
+	// IsPrivate reports the suffix matched a rule from the PRIVATE section
+	IsPrivate bool
+
+	// Rule is the rule that decided the suffix, nil when none matched
+	Rule *Rule
+
+	// Reason explains a result without a domain, Label is the offending label or host and Position
+	// the 1-based character position in the ASCII host of a ReasonIllegalCharacter, 0 otherwise
+	Reason   Reason
+	Label    string
+	Position int
+
+	// URL holds the components of the input, only set by ExtractURL
+	URL *URL
 }
 
 type TldNode struct {
 	ExceptRule bool
 	ValidTld   bool
-	matches    map[string]*TldNode
+	Section    Section
+	// Rule is the rule ending at this node, nil for nodes only on the path of longer rules
+	Rule    *Rule
+	matches map[string]*TldNode
 }
 
 type TLDExtract struct {
 	CacheTimeout int64
 	CacheFile    string
-	TldNodes     *TldNode
-	Debug        bool
+	// TldNodes holds the rules loaded by the constructor, Extract uses the latest refreshed rules
+	TldNodes *TldNode
+	Debug    bool
+
+	// IgnorePrivate matches ICANN rules only, so "foo.blogspot.com" yields Domain "blogspot" Tld "com"
+	IgnorePrivate bool
+
+	// WHATWG finds the host as a browser does, following the WHATWG URL Standard, see WithWHATWG
+	WHATWG bool
+
+	options *options
+	state   *ruleState
 }
 
 func New(fqdn string, debug bool) (*TLDExtract, error) {
+	return NewContext(context.Background(), fqdn, debug)
+}
+
+// NewContext - New that gives up loading the list when ctx is done
+func NewContext(ctx context.Context, fqdn string, debug bool) (*TLDExtract, error) {
+	if fqdn == "" {
+		return nil, fmt.Errorf("cache file name is empty")
+	}
+	return NewWithOptionsContext(ctx, WithCacheFile(fqdn), WithDebug(debug))
+}
+
+// NewWithOptions - create TLDExtract configured by opts, TLDEXTRACT_CACHE_TIMEOUT and TLDEXTRACT_URLS supply the defaults
+func NewWithOptions(opts ...Option) (*TLDExtract, error) {
+	return NewWithOptionsContext(context.Background(), opts...)
+}
+
+// NewWithOptionsContext - NewWithOptions that gives up loading the list when ctx is done
+func NewWithOptionsContext(ctx context.Context, opts ...Option) (*TLDExtract, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	o.resolveClient()
+
 	// Load Unique Cache List
-	timeout := GetEnvInt64("TLDEXTRACT_CACHE_TIMEOUT", 10, 64, DefaultCacheTimeout)
-	urlsString := GetEnvString("TLDEXTRACT_URLS", strings.Join(DefaultTldUrls, ","))
-	urls := strings.Split(urlsString, ",")
-	cache, err := LoadCache(fqdn, urls, true, timeout)
+	cache, denied, loadedAt, err := o.loadRules(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Load Unique Cache List into TldNode structure
+	tld := newTLDExtract(o, cache, loadedAt)
+	tld.state.denied = denied
+	if o.autoRefresh > 0 {
+		tld.state.startAutoRefresh(tld, o.autoRefresh)
+	}
+	return tld, nil
+}
+
+// NewEmbedded - create TLDExtract from the embedded public suffix list snapshot only, without network or cache file
+func NewEmbedded(debug bool) (*TLDExtract, error) {
+	cache, err := LoadEmbedded()
+	if err != nil {
+		return nil, err
+	}
+
+	o := &options{refresh: RefreshNever, timeout: DefaultCacheTimeout, embeddedFallback: true, privateRules: true, debug: debug}
+	return newTLDExtract(o, cache, time.Time{}), nil
+}
+
+func newTLDExtract(o *options, cache *RuleSet, loadedAt time.Time) *TLDExtract {
+	state := newRuleState(cache, loadedAt)
+	tld := TLDExtract{
+		CacheFile:     o.cacheFile,
+		CacheTimeout:  o.timeout,
+		Debug:         o.debug,
+		TldNodes:      state.snapshot().nodes,
+		IgnorePrivate: !o.privateRules,
+		WHATWG:        o.whatwg,
+		options:       o,
+		state:         state,
+	}
+	return &tld
+}
+
+// newTldNodes - Load Unique Cache List into TldNode structure
+func newTldNodes(cache *RuleSet) *TldNode {
 	newEmptyMap := make(map[string]*TldNode)
 	tldNodes := &TldNode{ExceptRule: false, ValidTld: false, matches: newEmptyMap}
-	for key, _ := range cache {
-		exceptionRule := key[0] == '!'
-		if exceptionRule {
-			key = key[1:]
+	for _, rule := range cache.Rules() {
+		rule := rule
+		parts := rule.Labels()
+		for idx, part := range parts {
+			// Store rules as A-labels so Unicode and punycode input match the same node
+			if ascii, err := ToASCII(part); err == nil {
+				parts[idx] = ascii
+			}
 		}
-		parts := strings.Split(key, ".")
-		addTldRule(tldNodes, parts, exceptionRule)
+		addTldRule(tldNodes, parts, &rule)
 	}
+	return tldNodes
+}
 
-	tld := TLDExtract{
-		CacheFile:    fqdn,
-		CacheTimeout: timeout,
-		Debug:        debug,
-		TldNodes:     tldNodes,
-	}
-	return &tld, nil
+// ICANNOnly - return a copy of tlde sharing the same rules and refreshes that ignores PRIVATE section rules
+func (tlde *TLDExtract) ICANNOnly() *TLDExtract {
+	icann := *tlde
+	icann.IgnorePrivate = true
+	return &icann
 }
 
-func addTldRule(rootNode *TldNode, parts []string, ex bool) {
+func addTldRule(rootNode *TldNode, parts []string, rule *Rule) {
+	ex := rule.Type == RuleException
+	section := rule.Section
 	numParts := len(parts)
 	current := rootNode
 	for idx := numParts - 1; idx >= 0; idx-- {
 		lab := parts[idx]
 		match, found := current.matches[lab]
 		if !found {
-			except := ex
+			// Only the last label of an exception rule is the exception, parents are plain path nodes
+			except := ex && idx == 0
 			valid := !ex && idx == 0
 			newEmptyMap := make(map[string]*TldNode)
-			current.matches[lab] = &TldNode{ExceptRule: except, ValidTld: valid, matches: newEmptyMap}
+			current.matches[lab] = &TldNode{ExceptRule: except, ValidTld: valid, Section: section, matches: newEmptyMap}
 			match = current.matches[lab]
-		} else if !ex && idx == 0 {
-			match.ValidTld = true
+			if idx == 0 {
+				match.Rule = rule
+			}
+		} else if idx == 0 {
+			match.ExceptRule = ex
+			match.ValidTld = !ex
+			match.Section = section
+			match.Rule = rule
 		}
 
 		current = match
@@ -105,6 +220,9 @@ func addTldRule(rootNode *TldNode, parts []string, ex bool) {
 }
 
 func (tlde *TLDExtract) Extract(urlString string) *Result {
+	if tlde.WHATWG {
+		return tlde.extractWHATWG(urlString)
+	}
 	data := strings.ToLower(urlString)
 
 	data = schemeRegex.ReplaceAllString(data, "")
@@ -113,15 +231,20 @@ func (tlde *TLDExtract) Extract(urlString string) *Result {
 		data = data[atIdx+1:]
 	}
 
-	index := strings.IndexFunc(data, func(r rune) bool {
-		switch r {
-		case '&', '/', '?', ':', '#':
-			return true
+	// IPv6 literals contain ':' so they must be pulled out before cutting at the port
+	if host, ok := ipv6Host(data); ok {
+		data = host
+	} else {
+		index := strings.IndexFunc(data, func(r rune) bool {
+			switch r {
+			case '&', '/', '?', ':', '#':
+				return true
+			}
+			return false
+		})
+		if index != -1 {
+			data = data[0:index]
 		}
-		return false
-	})
-	if index != -1 {
-		data = data[0:index]
 	}
 
 	if tlde.Debug {
@@ -131,29 +254,91 @@ func (tlde *TLDExtract) Extract(urlString string) *Result {
 	return tlde.extract(data)
 }
 
+// ipv6Host - return the IPv6 literal from a bracketed "[addr]:port" or bare "addr" host, an
+// unterminated bracket is returned as is
+func ipv6Host(data string) (string, bool) {
+	if strings.HasPrefix(data, "[") {
+		end := strings.IndexByte(data, ']')
+		if end == -1 {
+			return data, true
+		}
+		// RFC 6874 encodes the zone separator as "%25" inside URIs
+		return strings.Replace(data[1:end], "%25", "%", 1), true
+	}
+	index := strings.IndexAny(data, "&/?#")
+	if index != -1 {
+		data = data[0:index]
+	}
+	if strings.Count(data, ":") < 2 {
+		return "", false
+	}
+	return data, true
+}
+
 func (tlde *TLDExtract) extract(url string) *Result {
-	domain, tld := tlde.extractTld(url)
+	if url == "" {
+		return rejected(NoHost, ReasonEmptyInput, "")
+	}
+	if strings.Contains(url, ":") || strings.HasPrefix(url, "[") {
+		if addr, ok := NormalizeIPv6(url); ok {
+			return &Result{Flag: IPv6, Domain: addr, DomainASCII: addr}
+		}
+		return rejected(Malformed, ReasonInvalidIP, url)
+	}
+	host, err := ToASCII(url)
+	if err != nil {
+		return rejected(InvalidLabel, ReasonInvalidIDNA, invalidIDNALabel(url))
+	}
+	if result := checkLengths(host); result != nil {
+		return result
+	}
+	domain, tld, node := tlde.extractTld(host)
 	if tld == "" {
-		ip := net.ParseIP(url)
+		ip := net.ParseIP(host)
 		if ip != nil {
 			if IsIPv4(ip) {
-				return &Result{Flag: IPv4, Domain: url}
+				return &Result{Flag: IPv4, Domain: host, DomainASCII: host}
 			}
-			return &Result{Flag: Malformed, Domain: url}
+			result := rejected(Malformed, ReasonInvalidIP, host)
+			result.Domain = host
+			return result
+		}
+		if looksLikeIPv4(host) {
+			return rejected(Malformed, ReasonInvalidIP, host)
 		}
-		return &Result{Flag: Malformed}
+		return rejected(UnknownTLD, ReasonUnknownSuffix, host[strings.LastIndexByte(host, '.')+1:])
 	}
 	subDomain, domain := SubDomain(domain)
-	if domainRegex.MatchString(domain) {
-		return &Result{Flag: Domain, Domain: domain, SubDomain: subDomain, Tld: tld}
+	if !domainRegex.MatchString(domain) {
+		return rejectDomain(host, subDomain, domain)
 	}
-	return &Result{Flag: Malformed}
+	return &Result{
+		Flag:           Domain,
+		SubDomain:      ToUnicode(subDomain),
+		Domain:         ToUnicode(domain),
+		Tld:            ToUnicode(tld),
+		SubDomainASCII: subDomain,
+		DomainASCII:    domain,
+		TldASCII:       tld,
+		IsPrivate:      node.Section == SectionPrivate,
+		Rule:           node.Rule,
+	}
+}
+
+// looksLikeIPv4 - report whether every label of host is a number, like an IPv4 address
+func looksLikeIPv4(host string) bool {
+	for _, label := range strings.Split(host, ".") {
+		if _, err := strconv.ParseUint(label, 10, 64); err != nil {
+			return false
+		}
+	}
+	return true
 }
 
-func (tlde *TLDExtract) extractTld(url string) (domain, tld string) {
+func (tlde *TLDExtract) extractTld(url string) (domain, tld string, node *TldNode) {
 	spl := strings.Split(url, ".")
-	tldIndex, validTld := tlde.getTldIndex(spl)
-	if validTld {
+	tldIndex, node := tlde.getTldIndex(spl)
+	if node != nil {
 		domain = strings.Join(spl[:tldIndex], ".")
 		tld = strings.Join(spl[tldIndex:], ".")
 	} else {
@@ -162,28 +347,36 @@ func (tlde *TLDExtract) extractTld(url string) (domain, tld string) {
 	return
 }
 
-func (tlde *TLDExtract) getTldIndex(labels []string) (int, bool) {
-	current := tlde.TldNodes
-	parentValid := false
+// usable - report whether node holds a rule this extractor should apply
+func (tlde *TLDExtract) usable(node *TldNode) bool {
+	return !(tlde.IgnorePrivate && node.Section == SectionPrivate)
+}
+
+// getTldIndex - return index of the first public suffix label and the node of the matching rule, nil if none matched
+func (tlde *TLDExtract) getTldIndex(labels []string) (int, *TldNode) {
+	current := tlde.nodes()
+	tldIndex := -1
+	var matched *TldNode
 	for idx := len(labels) - 1; idx >= 0; idx-- {
 		lab := labels[idx]
 		node, foundLabel := current.matches[lab]
-		_, foundAsterisk := current.matches["*"]
+		asterisk, foundAsterisk := current.matches["*"]
 
 		switch {
+		// Found an exception rule, the suffix is its parent
+		case foundLabel && node.ExceptRule && tlde.usable(node):
+			return idx + 1, node
 		case foundLabel && !node.ExceptRule:
-			parentValid = node.ValidTld
+			if node.ValidTld && tlde.usable(node) {
+				tldIndex, matched = idx, node
+			}
 			current = node
-		// Found an exception rule
-		case foundLabel:
-			fallthrough
-		case parentValid:
-			return idx + 1, true
-		case foundAsterisk:
-			parentValid = true
+		case foundAsterisk && tlde.usable(asterisk):
+			tldIndex, matched = idx, asterisk
+			current = asterisk
 		default:
-			return -1, false
+			return tldIndex, matched
 		}
 	}
-	return -1, false
+	return tldIndex, matched
 }
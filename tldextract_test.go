@@ -1,7 +1,16 @@
 package tldextract
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -18,7 +27,7 @@ func Test_New_empty_file(t *testing.T) {
 func Test_New_missing_cache_file(t *testing.T) {
 	assert := assert.New(t)
 
-	actual, err := New("i.do.not.exist.cache", false)
+	actual, err := New(filepath.Join(t.TempDir(), "i.do.not.exist.cache"), false)
 
 	assert.Nil(err, "Error nil")
 	assert.NotNil(actual, "Result not nil")
@@ -27,7 +36,13 @@ func Test_New_missing_cache_file(t *testing.T) {
 func Test_New_good_cache_file(t *testing.T) {
 	assert := assert.New(t)
 
-	actual, err := New("test/tld.cache", false)
+	// A copy, New refreshes a stale cache file
+	data, err := ReadFile("test/tld.cache")
+	assert.Nil(err, "Error nil")
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+	assert.Nil(WriteFile(cacheFile, data), "Error nil")
+
+	actual, err := New(cacheFile, false)
 
 	assert.Nil(err, "Error nil")
 	assert.NotNil(actual, "Result not nil")
@@ -46,7 +61,7 @@ func assertResult(t *testing.T, url string, expected *Result, actual *Result, de
 func Test_Extract(t *testing.T) {
 	assert := assert.New(t)
 
-	tld, err := New("test/tld.cache", false)
+	tld, err := NewWithOptions(WithCacheFile("test/tld.cache"), WithRefreshPolicy(RefreshNever))
 	assert.Nil(err, "Error nil")
 
 	testCases := []struct {
@@ -57,7 +72,7 @@ func Test_Extract(t *testing.T) {
 	}{
 		{
 			Url:            "",
-			ExpectedResult: Result{Flag: Malformed, SubDomain: "", Domain: "", Tld: ""},
+			ExpectedResult: Result{Flag: NoHost, SubDomain: "", Domain: "", Tld: ""},
 			ExpectedError:  nil,
 			Description:    "empty string",
 		},
@@ -135,7 +150,7 @@ func Test_Extract(t *testing.T) {
 		},
 		{
 			Url:            "git+ssh://www.!github.com/",
-			ExpectedResult: Result{Flag: Malformed, SubDomain: "", Domain: "", Tld: ""},
+			ExpectedResult: Result{Flag: InvalidLabel, SubDomain: "", Domain: "", Tld: ""},
 			ExpectedError:  nil,
 			Description:    "Full git+ssh URL with bad domain",
 		},
@@ -175,38 +190,90 @@ func Test_Extract(t *testing.T) {
 			ExpectedError:  nil,
 			Description:    "Basic IPv4 Address URL with bad IP",
 		},
-		/*
-			{
-				Url:            "http://2001:0db8:0000:0000:0000:ff00:0042:8329",
-				ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "2001:0db8:0000:0000:0000:ff00:0042:8329", Tld: ""},
-				ExpectedError:  nil,
-				Description:    "Basic IPv6 Address URL",
-			},
-			{
-				Url:            "http://2001:db8:0:0:0:ff00:42:8329",
-				ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "2001:db8:0:0:0:ff00:42:8329", Tld: ""},
-				ExpectedError:  nil,
-				Description:    "Basic IPv6 Address URL",
-			},
-			{
-				Url:            "http://2001:db8::ff00:42:8329",
-				ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "2001:db8::ff00:42:8329", Tld: ""},
-				ExpectedError:  nil,
-				Description:    "Basic IPv6 Address URL",
-			},
-			{
-				Url:            "http://::ffff:192.0.2.128",
-				ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "::ffff:192.0.2.128", Tld: ""},
-				ExpectedError:  nil,
-				Description:    "Basic IPv6 Address URL",
-			},
-			{
-				Url:            "http://::192.0.2.128",
-				ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "::192.0.2.128", Tld: ""},
-				ExpectedError:  nil,
-				Description:    "Basic IPv6 Address URL",
-			},
-		*/
+		{
+			Url:            "http://2001:0db8:0000:0000:0000:ff00:0042:8329",
+			ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "2001:db8::ff00:42:8329", Tld: ""},
+			ExpectedError:  nil,
+			Description:    "Basic IPv6 Address URL",
+		},
+		{
+			Url:            "http://2001:db8:0:0:0:ff00:42:8329",
+			ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "2001:db8::ff00:42:8329", Tld: ""},
+			ExpectedError:  nil,
+			Description:    "Basic IPv6 Address URL",
+		},
+		{
+			Url:            "http://2001:db8::ff00:42:8329",
+			ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "2001:db8::ff00:42:8329", Tld: ""},
+			ExpectedError:  nil,
+			Description:    "Basic IPv6 Address URL",
+		},
+		{
+			Url:            "http://::ffff:192.0.2.128",
+			ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "::ffff:192.0.2.128", Tld: ""},
+			ExpectedError:  nil,
+			Description:    "IPv4-mapped IPv6 Address URL",
+		},
+		{
+			Url:            "http://::192.0.2.128",
+			ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "::c000:280", Tld: ""},
+			ExpectedError:  nil,
+			Description:    "IPv4-compatible IPv6 Address URL",
+		},
+		{
+			Url:            "2001:DB8::1",
+			ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "2001:db8::1", Tld: ""},
+			ExpectedError:  nil,
+			Description:    "Bare IPv6 Address",
+		},
+		{
+			Url:            "http://[2001:db8::1]:8443/x",
+			ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "2001:db8::1", Tld: ""},
+			ExpectedError:  nil,
+			Description:    "Bracketed IPv6 Address URL with port",
+		},
+		{
+			Url:            "https://user:pass@[2001:db8::1]/path?q=1",
+			ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "2001:db8::1", Tld: ""},
+			ExpectedError:  nil,
+			Description:    "Bracketed IPv6 Address URL with userinfo",
+		},
+		{
+			Url:            "[::ffff:10.10.10.10]",
+			ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "::ffff:10.10.10.10", Tld: ""},
+			ExpectedError:  nil,
+			Description:    "Bracketed IPv4-mapped IPv6 Address",
+		},
+		{
+			Url:            "http://[fe80::1%25eth0]:80/",
+			ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "fe80::1%eth0", Tld: ""},
+			ExpectedError:  nil,
+			Description:    "Bracketed IPv6 Address URL with encoded zone",
+		},
+		{
+			Url:            "fe80::1%eth0",
+			ExpectedResult: Result{Flag: IPv6, SubDomain: "", Domain: "fe80::1%eth0", Tld: ""},
+			ExpectedError:  nil,
+			Description:    "Bare IPv6 Address with zone",
+		},
+		{
+			Url:            "http://[2001:db8::1",
+			ExpectedResult: Result{Flag: Malformed, SubDomain: "", Domain: "", Tld: ""},
+			ExpectedError:  nil,
+			Description:    "Bracketed IPv6 Address URL missing bracket",
+		},
+		{
+			Url:            "http://[2001:db8::g]/",
+			ExpectedResult: Result{Flag: Malformed, SubDomain: "", Domain: "", Tld: ""},
+			ExpectedError:  nil,
+			Description:    "Bracketed IPv6 Address URL with bad IP",
+		},
+		{
+			Url:            "http://[fe80::1%25]/",
+			ExpectedResult: Result{Flag: Malformed, SubDomain: "", Domain: "", Tld: ""},
+			ExpectedError:  nil,
+			Description:    "Bracketed IPv6 Address URL with empty zone",
+		},
 		{
 			Url:            "http://godaddy.godaddy",
 			ExpectedResult: Result{Flag: Domain, SubDomain: "", Domain: "godaddy", Tld: "godaddy"},
@@ -233,13 +300,13 @@ func Test_Extract(t *testing.T) {
 		},
 		{
 			Url:            "http://godaddy.cannon-fodder",
-			ExpectedResult: Result{Flag: Malformed, SubDomain: "", Domain: "", Tld: ""},
+			ExpectedResult: Result{Flag: UnknownTLD, SubDomain: "", Domain: "", Tld: ""},
 			ExpectedError:  nil,
 			Description:    "Basic URL with bad TLD",
 		},
 		{
 			Url:            "http://godaddy.godaddy.cannon-fodder",
-			ExpectedResult: Result{Flag: Malformed, SubDomain: "", Domain: "", Tld: ""},
+			ExpectedResult: Result{Flag: UnknownTLD, SubDomain: "", Domain: "", Tld: ""},
 			ExpectedError:  nil,
 			Description:    "Basic URL with subdomainand bad TLD",
 		},
@@ -264,3 +331,251 @@ func Test_Extract(t *testing.T) {
 		assertResult(t, tc.Url, &tc.ExpectedResult, actualResult, tc.Description)
 	}
 }
+
+func Test_Extract_IDNA(t *testing.T) {
+	assert := assert.New(t)
+
+	tld, err := NewWithOptions(WithCacheFile("test/idn.cache"), WithRefreshPolicy(RefreshNever))
+	assert.Nil(err, "Error nil")
+
+	testCases := []struct {
+		Url            string
+		ExpectedResult Result
+		Description    string
+	}{
+		{
+			Url:            "http://www.bücher.de/",
+			ExpectedResult: Result{Flag: Domain, SubDomain: "www", Domain: "bücher", Tld: "de", SubDomainASCII: "www", DomainASCII: "xn--bcher-kva", TldASCII: "de"},
+			Description:    "Unicode domain",
+		},
+		{
+			Url:            "http://www.xn--bcher-kva.de/",
+			ExpectedResult: Result{Flag: Domain, SubDomain: "www", Domain: "bücher", Tld: "de", SubDomainASCII: "www", DomainASCII: "xn--bcher-kva", TldASCII: "de"},
+			Description:    "punycode domain",
+		},
+		{
+			Url:            "WWW.BÜCHER.DE",
+			ExpectedResult: Result{Flag: Domain, SubDomain: "www", Domain: "bücher", Tld: "de", SubDomainASCII: "www", DomainASCII: "xn--bcher-kva", TldASCII: "de"},
+			Description:    "uppercase Unicode domain",
+		},
+		{
+			Url:            "пример.рф",
+			ExpectedResult: Result{Flag: Domain, SubDomain: "", Domain: "пример", Tld: "рф", SubDomainASCII: "", DomainASCII: "xn--e1afmkfd", TldASCII: "xn--p1ai"},
+			Description:    "Cyrillic domain and TLD",
+		},
+		{
+			Url:            "xn--e1afmkfd.xn--p1ai",
+			ExpectedResult: Result{Flag: Domain, SubDomain: "", Domain: "пример", Tld: "рф", SubDomainASCII: "", DomainASCII: "xn--e1afmkfd", TldASCII: "xn--p1ai"},
+			Description:    "punycode Cyrillic domain and TLD",
+		},
+		{
+			Url:            "example.xn--55qx5d.cn",
+			ExpectedResult: Result{Flag: Domain, SubDomain: "", Domain: "example", Tld: "公司.cn", SubDomainASCII: "", DomainASCII: "example", TldASCII: "xn--55qx5d.cn"},
+			Description:    "punycode input matching Unicode rule",
+		},
+		{
+			Url:            "http://domainer.公司.香港",
+			ExpectedResult: Result{Flag: Domain, SubDomain: "", Domain: "domainer", Tld: "公司.香港", SubDomainASCII: "", DomainASCII: "domainer", TldASCII: "xn--55qx5d.xn--j6w193g"},
+			Description:    "fully Unicode TLD",
+		},
+		{
+			Url:            "http://xn--a.de/",
+			ExpectedResult: Result{Flag: InvalidLabel, Reason: ReasonInvalidIDNA, Label: "xn--a"},
+			Description:    "invalid punycode",
+		},
+	}
+
+	for _, tc := range testCases {
+		actual := *tld.Extract(tc.Url)
+		// the matched rule is covered by Test_Extract_rule
+		actual.Rule = nil
+
+		assert.Equal(tc.ExpectedResult, actual, tc.Description)
+	}
+}
+
+func Test_Extract_Sections(t *testing.T) {
+	assert := assert.New(t)
+
+	tld, err := NewWithOptions(WithCacheFile("test/sections.cache"), WithRefreshPolicy(RefreshNever))
+	assert.Nil(err, "Error nil")
+	icann := tld.ICANNOnly()
+
+	testCases := []struct {
+		Url                 string
+		ExpectedResult      Result
+		ExpectedICANNResult Result
+		Description         string
+	}{
+		{
+			Url:                 "foo.blogspot.com",
+			ExpectedResult:      Result{Flag: Domain, SubDomain: "", Domain: "foo", Tld: "blogspot.com", IsPrivate: true},
+			ExpectedICANNResult: Result{Flag: Domain, SubDomain: "foo", Domain: "blogspot", Tld: "com"},
+			Description:         "private suffix",
+		},
+		{
+			Url:                 "www.foo.blogspot.co.uk",
+			ExpectedResult:      Result{Flag: Domain, SubDomain: "www", Domain: "foo", Tld: "blogspot.co.uk", IsPrivate: true},
+			ExpectedICANNResult: Result{Flag: Domain, SubDomain: "www.foo", Domain: "blogspot", Tld: "co.uk"},
+			Description:         "private suffix below ICANN suffix",
+		},
+		{
+			Url:                 "a.b.compute.amazonaws.com",
+			ExpectedResult:      Result{Flag: Domain, SubDomain: "", Domain: "a", Tld: "b.compute.amazonaws.com", IsPrivate: true},
+			ExpectedICANNResult: Result{Flag: Domain, SubDomain: "a.b.compute", Domain: "amazonaws", Tld: "com"},
+			Description:         "private wildcard suffix",
+		},
+		{
+			Url:                 "www.example.co.uk",
+			ExpectedResult:      Result{Flag: Domain, SubDomain: "www", Domain: "example", Tld: "co.uk"},
+			ExpectedICANNResult: Result{Flag: Domain, SubDomain: "www", Domain: "example", Tld: "co.uk"},
+			Description:         "ICANN suffix",
+		},
+		{
+			Url:                 "www.foo.kawasaki.jp",
+			ExpectedResult:      Result{Flag: Domain, SubDomain: "", Domain: "www", Tld: "foo.kawasaki.jp"},
+			ExpectedICANNResult: Result{Flag: Domain, SubDomain: "", Domain: "www", Tld: "foo.kawasaki.jp"},
+			Description:         "ICANN wildcard suffix",
+		},
+		{
+			Url:                 "www.city.kawasaki.jp",
+			ExpectedResult:      Result{Flag: Domain, SubDomain: "www", Domain: "city", Tld: "kawasaki.jp"},
+			ExpectedICANNResult: Result{Flag: Domain, SubDomain: "www", Domain: "city", Tld: "kawasaki.jp"},
+			Description:         "ICANN exception rule",
+		},
+	}
+
+	for _, tc := range testCases {
+		actual := tld.Extract(tc.Url)
+		assertResult(t, tc.Url, &tc.ExpectedResult, actual, tc.Description)
+		assert.Equal(tc.ExpectedResult.IsPrivate, actual.IsPrivate, tc.Description)
+
+		actual = icann.Extract(tc.Url)
+		assertResult(t, tc.Url, &tc.ExpectedICANNResult, actual, tc.Description+" (ICANN only)")
+		assert.Equal(tc.ExpectedICANNResult.IsPrivate, actual.IsPrivate, tc.Description+" (ICANN only)")
+	}
+}
+
+func Test_addTldRule_exception_order(t *testing.T) {
+	assert := assert.New(t)
+
+	rules := [][]string{{"jp"}, {"kawasaki", "jp"}, {"*", "kawasaki", "jp"}, {"city", "kawasaki", "jp"}}
+	for _, order := range [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}} {
+		root := &TldNode{matches: make(map[string]*TldNode)}
+		for _, idx := range order {
+			text := strings.Join(rules[idx], ".")
+			if idx == 3 {
+				text = "!" + text
+			}
+			rule := NewRule(text, SectionICANN, "test", idx+1)
+			addTldRule(root, rules[idx], &rule)
+		}
+		tld := &TLDExtract{TldNodes: root}
+
+		actual := tld.Extract("www.city.kawasaki.jp")
+		assertResult(t, "www.city.kawasaki.jp", &Result{Flag: Domain, SubDomain: "www", Domain: "city", Tld: "kawasaki.jp"}, actual, "exception rule")
+
+		actual = tld.Extract("www.foo.kawasaki.jp")
+		assertResult(t, "www.foo.kawasaki.jp", &Result{Flag: Domain, SubDomain: "", Domain: "www", Tld: "foo.kawasaki.jp"}, actual, "wildcard rule")
+		assert.False(root.matches["jp"].matches["kawasaki"].ExceptRule, "parent of exception is not an exception")
+	}
+}
+
+func Test_NewContext_cancelled(t *testing.T) {
+	assert := assert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A copy, a stale cache file is locked for the refresh
+	data, err := ReadFile("test/tld.cache")
+	assert.Nil(err, "Error nil")
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+	assert.Nil(WriteFile(cacheFile, data), "Error nil")
+
+	actual, err := NewContext(ctx, cacheFile, false)
+
+	assert.Nil(actual, "Result should be nil")
+	assert.True(errors.Is(err, context.Canceled), "context error")
+}
+
+func Test_NewWithOptionsContext_deadline(t *testing.T) {
+	assert := assert.New(t)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	actual, err := NewWithOptionsContext(ctx, WithURLs(server.URL), WithTimeout(60))
+
+	assert.Nil(actual, "Result should be nil")
+	assert.True(errors.Is(err, context.DeadlineExceeded), "deadline error")
+	assert.Less(int64(time.Since(start)), int64(5*time.Second), "download abandoned")
+}
+
+func Test_RefreshContext(t *testing.T) {
+	assert := assert.New(t)
+	body := "com\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	tld, err := NewWithOptions(WithURLs(server.URL), WithRules("corp.example"), WithEmbeddedFallback(false))
+	assert.Nil(err, "Error nil")
+	assertResult(t, "example.org", &Result{Flag: UnknownTLD}, tld.Extract("example.org"), "rule not loaded yet")
+
+	body = "com\norg\n"
+	assert.Nil(tld.Refresh(), "Refresh error nil")
+	assertResult(t, "example.org", &Result{Flag: Domain, Domain: "example", Tld: "org"}, tld.Extract("example.org"), "refreshed rule")
+	assertResult(t, "a.b.corp.example", &Result{Flag: Domain, SubDomain: "a", Domain: "b", Tld: "corp.example"}, tld.Extract("a.b.corp.example"), "custom rule kept")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.True(errors.Is(tld.RefreshContext(ctx), context.Canceled), "cancelled refresh")
+	assertResult(t, "example.org", &Result{Flag: Domain, Domain: "example", Tld: "org"}, tld.Extract("example.org"), "rules kept after failed refresh")
+
+	assert.NotNil((&TLDExtract{}).Refresh(), "no list source")
+}
+
+func Test_New_cache_max_age(t *testing.T) {
+	assert := assert.New(t)
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprint(w, "com\norg\n")
+	}))
+	defer server.Close()
+	os.Setenv("TLDEXTRACT_URLS", server.URL)
+	defer os.Unsetenv("TLDEXTRACT_URLS")
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+	assert.Nil(WriteFile(cacheFile, []byte("com\n")), "write cache file")
+	written := time.Now().Add(-time.Hour)
+	assert.Nil(os.Chtimes(cacheFile, written, written), "age cache file")
+
+	tld, err := New(cacheFile, false)
+	assert.Nil(err, "Error nil")
+	assert.Equal(0, hits, "cache younger than max age is used")
+	assert.True(tld.LoadedAt().Equal(written), "LoadedAt is cache file time")
+	assert.InDelta(float64(time.Hour), float64(tld.ListAge()), float64(time.Minute), "ListAge")
+
+	os.Setenv("TLDEXTRACT_CACHE_MAX_AGE", "60")
+	defer os.Unsetenv("TLDEXTRACT_CACHE_MAX_AGE")
+	before := time.Now()
+	tld, err = New(cacheFile, false)
+	assert.Nil(err, "Error nil")
+	assert.Equal(1, hits, "cache older than max age is refreshed")
+	assert.False(tld.LoadedAt().Before(before), "LoadedAt is download time")
+	assertResult(t, "example.org", &Result{Flag: Domain, Domain: "example", Tld: "org"}, tld.Extract("example.org"), "refreshed list")
+
+	tld, err = NewEmbedded(false)
+	assert.Nil(err, "Error nil")
+	assert.True(tld.LoadedAt().IsZero(), "embedded snapshot has no fetch time")
+}
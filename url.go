@@ -0,0 +1,110 @@
+package tldextract
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultPorts - port of each scheme used by ExtractURL when the URL has none
+var DefaultPorts = map[string]int{
+	"ftp":     21,
+	"git":     9418,
+	"git+ssh": 22,
+	"http":    80,
+	"https":   443,
+	"sftp":    22,
+	"ssh":     22,
+	"ws":      80,
+	"wss":     443,
+}
+
+// URL - components of the input of ExtractURL. Path, Query and Fragment are kept raw, as escaped in the input.
+type URL struct {
+	// Scheme in lowercase, empty without a "scheme://" prefix
+	Scheme string
+	// User is the raw userinfo in front of '@', including any ":password"
+	User string
+	// Host in lowercase, without the brackets of an IPv6 literal
+	Host string
+	// Port is the explicit port, else the DefaultPorts entry of Scheme, 0 when neither is known
+	Port int
+	// DefaultPort reports Port comes from DefaultPorts
+	DefaultPort bool
+	Path        string
+	Query       string
+	Fragment    string
+}
+
+// ExtractURL - Extract that also returns every component of rawURL in Result.URL. A scheme is only
+// recognised in front of "//", as by Extract, so "example.com:8080/path" is a host with a port.
+// A port that is not a number from 0 to 65535 makes the result Malformed with ReasonInvalidPort.
+func (tlde *TLDExtract) ExtractURL(rawURL string) *Result {
+	parts, port, ok := splitURL(rawURL)
+
+	if tlde.Debug {
+		fmt.Printf("%s;%s\n", parts.Host, rawURL)
+	}
+
+	result := rejected(Malformed, ReasonInvalidPort, port)
+	if ok {
+		result = tlde.extract(parts.Host)
+	}
+	result.URL = &parts
+	return result
+}
+
+// splitURL - split rawURL into its components, ok is false when port, the text after the host, is not a port
+func splitURL(rawURL string) (parts URL, port string, ok bool) {
+	rest := rawURL
+	if idx := strings.IndexByte(rest, '#'); idx != -1 {
+		rest, parts.Fragment = rest[:idx], rest[idx+1:]
+	}
+	if idx := strings.IndexByte(rest, '?'); idx != -1 {
+		rest, parts.Query = rest[:idx], rest[idx+1:]
+	}
+	if loc := schemeRegex.FindStringIndex(strings.ToLower(rest)); loc != nil {
+		parts.Scheme = strings.TrimSuffix(strings.ToLower(rest[:loc[1]]), "//")
+		parts.Scheme = strings.TrimSuffix(parts.Scheme, ":")
+		rest = rest[loc[1]:]
+	}
+	authority := rest
+	if idx := strings.IndexByte(rest, '/'); idx != -1 {
+		authority, parts.Path = rest[:idx], rest[idx:]
+	}
+	if idx := strings.LastIndexByte(authority, '@'); idx != -1 {
+		parts.User, authority = authority[:idx], authority[idx+1:]
+	}
+
+	host := strings.ToLower(authority)
+	if strings.HasPrefix(host, "[") {
+		if end := strings.IndexByte(host, ']'); end != -1 && end+1 < len(host) {
+			host, port = host[:end+1], host[end+1:]
+			if !strings.HasPrefix(port, ":") {
+				parts.Host = host[1:end]
+				return parts, port, false
+			}
+			port = port[1:]
+		}
+		// Bracketed literal with the RFC 6874 zone separator decoded, an unterminated one as is
+		host, _ = ipv6Host(host)
+	} else if strings.Count(host, ":") == 1 {
+		// Bare IPv6 literals have more colons and no port, as in Extract
+		idx := strings.IndexByte(host, ':')
+		host, port = host[:idx], host[idx+1:]
+	}
+	parts.Host = host
+
+	if port == "" {
+		if defaultPort, found := DefaultPorts[parts.Scheme]; found {
+			parts.Port, parts.DefaultPort = defaultPort, true
+		}
+		return parts, port, true
+	}
+	number, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return parts, port, false
+	}
+	parts.Port = int(number)
+	return parts, port, true
+}
@@ -0,0 +1,134 @@
+package tldextract
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ValidationCheck - name of a check a downloaded list must pass
+type ValidationCheck string
+
+const (
+	CheckSectionMarkers ValidationCheck = "section-markers"
+	CheckMinRules       ValidationCheck = "min-rules"
+	CheckMaxShrink      ValidationCheck = "max-shrink"
+	CheckRuleSyntax     ValidationCheck = "rule-syntax"
+)
+
+// Validation - checks a downloaded list must pass before it replaces the cache
+type Validation struct {
+	// RequireSections rejects a source missing the ICANN or PRIVATE section markers
+	RequireSections bool
+	// MinRules rejects a merged list with fewer rules
+	MinRules int
+	// MaxShrink rejects a merged list that lost more than this fraction of the current cache file's rules, 0 disables
+	MaxShrink float64
+	// CheckSyntax rejects a source with any line that is not a valid rule
+	CheckSyntax bool
+}
+
+var (
+	// DefaultValidation - catches error pages and badly truncated lists while accepting small custom lists
+	DefaultValidation = Validation{MinRules: 1, MaxShrink: 0.5, CheckSyntax: true}
+
+	// StrictValidation - for the public suffix list itself, about 9000 rules in 2023
+	StrictValidation = Validation{RequireSections: true, MinRules: 5000, MaxShrink: 0.1, CheckSyntax: true}
+
+	ruleLabelRegex = regexp.MustCompile(`^[a-z0-9-]{1,63}$`)
+)
+
+// listValidation - the checks of downloaded lists, StrictValidation for DefaultTldUrls unless WithValidation
+// chose others
+func (o *options) listValidation() Validation {
+	if !o.validationSet && isDefaultURLs(o.urls) {
+		return StrictValidation
+	}
+	return o.validation
+}
+
+// isDefaultURLs - report whether urls are DefaultTldUrls
+func isDefaultURLs(urls []string) bool {
+	if len(urls) != len(DefaultTldUrls) {
+		return false
+	}
+	for idx, url := range urls {
+		if url != DefaultTldUrls[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidationError - a downloaded list failed Check, Source is the URL when a single source failed
+type ValidationError struct {
+	Check  ValidationCheck
+	Source string
+	Detail string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Source == "" {
+		return fmt.Sprintf("list rejected by %s check: %s", e.Check, e.Detail)
+	}
+	return fmt.Sprintf("list from '%s' rejected by %s check: %s", e.Source, e.Check, e.Detail)
+}
+
+// ValidateSource - apply the per source checks to the body downloaded from source
+func (v Validation) ValidateSource(source string, buffer string) error {
+	if v.RequireSections {
+		for _, marker := range []string{ICANNBeginMarker, ICANNEndMarker, PrivateBeginMarker, PrivateEndMarker} {
+			if !strings.Contains(buffer, marker) {
+				return &ValidationError{Check: CheckSectionMarkers, Source: source, Detail: "missing " + marker}
+			}
+		}
+	}
+	if v.CheckSyntax {
+		for idx, line := range strings.Split(buffer, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "//") {
+				continue
+			}
+			if !IsValidRule(line) {
+				return &ValidationError{Check: CheckRuleSyntax, Source: source, Detail: fmt.Sprintf("line %d: invalid rule %q", idx+1, line)}
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateList - apply the merged list checks to rules, replacing a cache file holding current rules
+func (v Validation) ValidateList(rules *RuleSet, current int) error {
+	if rules.Len() < v.MinRules {
+		return &ValidationError{Check: CheckMinRules, Detail: fmt.Sprintf("%d rules, at least %d required", rules.Len(), v.MinRules)}
+	}
+	if v.MaxShrink > 0 && current > 0 && float64(rules.Len()) < float64(current)*(1-v.MaxShrink) {
+		return &ValidationError{Check: CheckMaxShrink, Detail: fmt.Sprintf("%d rules replacing %d, more than %.0f%% lost", rules.Len(), current, v.MaxShrink*100)}
+	}
+	return nil
+}
+
+// IsValidRule - report whether rule is a public suffix list rule: dot separated labels, "*" wildcard
+// labels, and an optional leading "!" marking an exception. A rule of wildcards alone, which would make
+// every label a public suffix, is not valid.
+func IsValidRule(rule string) bool {
+	rule = strings.ToLower(rule)
+	if strings.HasPrefix(rule, "!") {
+		rule = rule[1:]
+		if !strings.Contains(rule, ".") {
+			return false
+		}
+	}
+	named := false
+	for _, label := range strings.Split(rule, ".") {
+		if label == "*" {
+			continue
+		}
+		ascii, err := ToASCII(label)
+		if err != nil || !ruleLabelRegex.MatchString(ascii) {
+			return false
+		}
+		named = true
+	}
+	return named
+}
@@ -0,0 +1,196 @@
+package tldextract
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IsValidRule(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		rule  string
+		valid bool
+	}{
+		{rule: "com", valid: true},
+		{rule: "co.uk", valid: true},
+		{rule: "*.ck", valid: true},
+		{rule: "*", valid: false},
+		{rule: "*.*", valid: false},
+		{rule: "!www.ck", valid: true},
+		{rule: "个人.hk", valid: true},
+		{rule: "xn--55qx5d.hk", valid: true},
+		{rule: "!ck", valid: false},
+		{rule: "", valid: false},
+		{rule: "co..uk", valid: false},
+		{rule: "<html>", valid: false},
+		{rule: "foo bar", valid: false},
+		{rule: "foo_bar.com", valid: false},
+		{rule: strings.Repeat("a", 64) + ".com", valid: false},
+	}
+	for _, test := range tests {
+		assert.Equal(test.valid, IsValidRule(test.rule), test.rule)
+	}
+}
+
+func Test_Validation_ValidateSource(t *testing.T) {
+	assert := assert.New(t)
+	tests := []struct {
+		validation Validation
+		body       string
+		check      ValidationCheck
+	}{
+		{validation: DefaultValidation, body: testListBody},
+		{validation: StrictValidation, body: testListBody},
+		{validation: DefaultValidation, body: "com\nnet\n"},
+		{validation: StrictValidation, body: "com\nnet\n", check: CheckSectionMarkers},
+		{validation: DefaultValidation, body: "<html><body>Sign in to the network</body></html>", check: CheckRuleSyntax},
+		{validation: Validation{}, body: "<html><body>Sign in to the network</body></html>"},
+	}
+	for _, test := range tests {
+		err := test.validation.ValidateSource("http://list", test.body)
+		if test.check == "" {
+			assert.Nil(err, test.body)
+			continue
+		}
+		var vErr *ValidationError
+		if assert.True(errors.As(err, &vErr), test.body) {
+			assert.Equal(test.check, vErr.Check, test.body)
+			assert.Equal("http://list", vErr.Source, test.body)
+		}
+	}
+}
+
+func Test_Validation_ValidateList(t *testing.T) {
+	assert := assert.New(t)
+	list := ParseRules("test", "com\nnet\norg\n")
+	tests := []struct {
+		validation Validation
+		current    int
+		check      ValidationCheck
+	}{
+		{validation: DefaultValidation, current: 0},
+		{validation: DefaultValidation, current: 6},
+		{validation: DefaultValidation, current: 7, check: CheckMaxShrink},
+		{validation: StrictValidation, current: 0, check: CheckMinRules},
+		{validation: Validation{}, current: 1000},
+	}
+	for _, test := range tests {
+		msg := fmt.Sprintf("%+v replacing %d", test.validation, test.current)
+		err := test.validation.ValidateList(list, test.current)
+		if test.check == "" {
+			assert.Nil(err, msg)
+			continue
+		}
+		var vErr *ValidationError
+		if assert.True(errors.As(err, &vErr), msg) {
+			assert.Equal(test.check, vErr.Check, msg)
+		}
+	}
+}
+
+func Test_CreateNewCacheFile_rejects_error_page(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := newListServer(t, "<html><body>Sign in to the network</body></html>")
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+	assert.Nil(WriteFile(cacheFile, []byte(testListBody)), "seed cache")
+
+	_, err := CreateNewCacheFile(cacheFile, []string{server.URL}, 5)
+	var vErr *ValidationError
+	if assert.True(errors.As(err, &vErr), "validation error") {
+		assert.Equal(CheckRuleSyntax, vErr.Check, "check")
+		assert.Equal(server.URL, vErr.Source, "source")
+	}
+	data, _ := os.ReadFile(cacheFile)
+	assert.Equal(testListBody, string(data), "cache file kept")
+}
+
+func Test_CreateNewCacheFile_rejects_shrunk_list(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := newListServer(t, "com\n")
+	cacheFile := filepath.Join(t.TempDir(), "tld.cache")
+	assert.Nil(WriteFile(cacheFile, []byte(testListBody)), "seed cache")
+
+	tld, err := NewWithOptions(
+		WithCacheFile(cacheFile),
+		WithURLs(server.URL),
+		WithHTTPClient(server.Client()),
+		WithRefreshPolicy(RefreshAlways),
+	)
+	assert.Nil(err, "falls back to the existing cache")
+	assertResult(t, "foo.blogspot.com", &Result{Flag: Domain, Domain: "foo", Tld: "blogspot.com", IsPrivate: true}, tld.Extract("foo.blogspot.com"), "cached list")
+
+	_, err = CreateNewCacheFile(cacheFile, []string{server.URL}, 5)
+	var vErr *ValidationError
+	if assert.True(errors.As(err, &vErr), "validation error") {
+		assert.Equal(CheckMaxShrink, vErr.Check, "check")
+	}
+	data, _ := os.ReadFile(cacheFile)
+	assert.Equal(testListBody, string(data), "cache file kept")
+}
+
+func Test_NewWithOptions_strict_validation(t *testing.T) {
+	assert := assert.New(t)
+	server, _ := newListServer(t, testListBody)
+
+	_, err := NewWithOptions(
+		WithURLs(server.URL),
+		WithHTTPClient(server.Client()),
+		WithValidation(StrictValidation),
+		WithEmbeddedFallback(false),
+	)
+	var vErr *ValidationError
+	if assert.True(errors.As(err, &vErr), "validation error") {
+		assert.Equal(CheckMinRules, vErr.Check, "check")
+	}
+}
+
+// redirectTransport - send every request to the test server, whatever its URL
+type redirectTransport struct {
+	server *httptest.Server
+}
+
+func (r redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = "http", strings.TrimPrefix(r.server.URL, "http://")
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func Test_NewWithOptions_default_urls_strict(t *testing.T) {
+	assert := assert.New(t)
+	truncated := "// ===BEGIN ICANN DOMAINS===\ncom\nnet\norg\nco.uk\n"
+	server, _ := newListServer(t, truncated)
+	client := &http.Client{Transport: redirectTransport{server: server}}
+
+	_, err := NewWithOptions(
+		WithURLs(DefaultTldUrls...),
+		WithHTTPClient(client),
+		WithEmbeddedFallback(false),
+	)
+	var vErr *ValidationError
+	if assert.True(errors.As(err, &vErr), "truncated public suffix list rejected on the first download") {
+		assert.Equal(CheckSectionMarkers, vErr.Check, "check")
+	}
+
+	_, err = NewWithOptions(
+		WithURLs(DefaultTldUrls...),
+		WithHTTPClient(client),
+		WithValidation(DefaultValidation),
+		WithEmbeddedFallback(false),
+	)
+	assert.Nil(err, "explicit validation applies to the default URLs")
+
+	_, err = NewWithOptions(
+		WithURLs(server.URL),
+		WithHTTPClient(client),
+		WithEmbeddedFallback(false),
+	)
+	assert.Nil(err, "DefaultValidation for other URLs")
+}
@@ -0,0 +1,635 @@
+package tldextract
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// whatwgProfile - domain to ASCII of the URL Standard with beStrict unset
+var whatwgProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.CheckHyphens(false), idna.StrictDomainName(false), idna.Transitional(false))
+
+// specialSchemes - default port of each special scheme of the URL Standard, "" for none
+var specialSchemes = map[string]string{
+	"file":  "",
+	"ftp":   "21",
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+}
+
+// WHATWGURL - scheme, host and port of a URL as parsed by the WHATWG URL Standard
+type WHATWGURL struct {
+	// Scheme in lowercase, without the ':'
+	Scheme string
+	// Host serialized as a browser does: lowercase ASCII, IPv4 in dotted decimal, IPv6 compressed
+	// in brackets. Empty for a URL without host, such as "mailto:" or "file:" URLs.
+	Host string
+	// Port in decimal, empty when absent or the default port of Scheme
+	Port string
+
+	// opaquePath reports a non-special URL without '/' after the scheme, which cannot be a base
+	opaquePath bool
+}
+
+// WHATWGError - Input is not a valid URL, Host is the host text that failed to parse if any
+type WHATWGError struct {
+	Input  string
+	Host   string
+	Detail string
+}
+
+func (e *WHATWGError) Error() string {
+	return fmt.Sprintf("'%s' is not a valid URL: %s", e.Input, e.Detail)
+}
+
+// ParseWHATWG - parse input resolved against base, unless base is empty, following the basic URL parser
+// of the WHATWG URL Standard as far as the scheme, host and port go
+func ParseWHATWG(input, base string) (*WHATWGURL, error) {
+	var baseURL *WHATWGURL
+	if base != "" {
+		parsed, err := parseWHATWG(base, nil)
+		if err != nil {
+			return nil, err
+		}
+		baseURL = parsed
+	}
+	return parseWHATWG(input, baseURL)
+}
+
+func parseWHATWG(input string, base *WHATWGURL) (*WHATWGURL, error) {
+	fail := func(host, format string, v ...interface{}) (*WHATWGURL, error) {
+		return nil, &WHATWGError{Input: input, Host: host, Detail: fmt.Sprintf(format, v...)}
+	}
+
+	rest := strings.TrimFunc(input, func(r rune) bool { return r <= ' ' })
+	rest = strings.NewReplacer("\t", "", "\n", "", "\r", "").Replace(rest)
+
+	scheme, afterScheme, found := cutScheme(rest)
+	url := &WHATWGURL{Scheme: scheme}
+	if !found {
+		if base == nil || (base.opaquePath && !strings.HasPrefix(rest, "#")) {
+			return fail("", "relative URL without a base")
+		}
+		// Relative reference, the host is the base host unless the input starts with an authority
+		url.Scheme = base.Scheme
+		authority, ok := cutSlashes(rest, base.Scheme)
+		if !ok {
+			url.Host, url.Port = base.Host, base.Port
+			return url, nil
+		}
+		return url.parseAuthority(authority, fail)
+	}
+
+	rest = afterScheme
+	switch {
+	case scheme == "file":
+		authority, ok := cutSlashes(rest, scheme)
+		if !ok {
+			if base != nil && base.Scheme == "file" {
+				url.Host = base.Host
+			}
+			return url, nil
+		}
+		return url.parseAuthority(authority, fail)
+	case isSpecial(scheme):
+		if base != nil && base.Scheme == scheme {
+			// "http:path" is relative to a base of the same scheme
+			authority, ok := cutSlashes(rest, scheme)
+			if !ok {
+				url.Host, url.Port = base.Host, base.Port
+				return url, nil
+			}
+			return url.parseAuthority(authority, fail)
+		}
+		return url.parseAuthority(strings.TrimLeft(rest, `/\`), fail)
+	case strings.HasPrefix(rest, "//"):
+		return url.parseAuthority(rest[2:], fail)
+	}
+	url.opaquePath = !strings.HasPrefix(rest, "/")
+	return url, nil
+}
+
+// cutScheme - split off a leading scheme and its ':', lowercasing it
+func cutScheme(input string) (string, string, bool) {
+	for idx := 0; idx < len(input); idx++ {
+		c := input[idx]
+		switch {
+		case isASCIIAlpha(c):
+		case idx > 0 && (isASCIIDigit(c) || c == '+' || c == '-' || c == '.'):
+		case idx > 0 && c == ':':
+			return strings.ToLower(input[:idx]), input[idx+1:], true
+		default:
+			return "", input, false
+		}
+	}
+	return "", input, false
+}
+
+// cutSlashes - the authority after two leading slashes, where a backslash counts as a slash for special
+// schemes, which ignore any further slashes except for "file"
+func cutSlashes(input, scheme string) (string, bool) {
+	special := isSpecial(scheme)
+	isSlash := func(c byte) bool {
+		return c == '/' || (special && c == '\\')
+	}
+	if len(input) < 2 || !isSlash(input[0]) || !isSlash(input[1]) {
+		return "", false
+	}
+	if !special || scheme == "file" {
+		return input[2:], true
+	}
+	return strings.TrimLeft(input[2:], `/\`), true
+}
+
+func isSpecial(scheme string) bool {
+	_, found := specialSchemes[scheme]
+	return found
+}
+
+// parseAuthority - parse the host and port at the start of input, up to the path, query or fragment
+func (url *WHATWGURL) parseAuthority(input string, fail func(string, string, ...interface{}) (*WHATWGURL, error)) (*WHATWGURL, error) {
+	special := isSpecial(url.Scheme)
+	end := strings.IndexAny(input, "/?#")
+	if special {
+		end = strings.IndexAny(input, `/?#\`)
+	}
+	authority := input
+	if end != -1 {
+		authority = input[:end]
+	}
+	if url.Scheme == "file" {
+		switch {
+		case isWindowsDriveLetter(authority):
+			// "file://c:/" is a path, not a host
+		case authority != "":
+			host, err := parseHost(authority, false)
+			if err != nil {
+				return fail(authority, "%s", err)
+			}
+			if host != "localhost" {
+				url.Host = host
+			}
+		}
+		return url, nil
+	}
+
+	if idx := strings.LastIndexByte(authority, '@'); idx != -1 {
+		authority = authority[idx+1:]
+		if authority == "" {
+			return fail("", "credentials without a host")
+		}
+	}
+	host, port := authority, ""
+	if idx := portSeparator(authority); idx != -1 {
+		host, port = authority[:idx], authority[idx+1:]
+		if host == "" {
+			return fail("", "port without a host")
+		}
+	}
+	if host == "" {
+		if special {
+			return fail("", "empty host")
+		}
+		return url, nil
+	}
+	parsed, err := parseHost(host, !special)
+	if err != nil {
+		return fail(host, "%s", err)
+	}
+	url.Host = parsed
+
+	if port == "" {
+		return url, nil
+	}
+	for idx := 0; idx < len(port); idx++ {
+		if !isASCIIDigit(port[idx]) {
+			return fail("", "invalid port '%s'", port)
+		}
+	}
+	number, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return fail("", "port '%s' out of range", port)
+	}
+	url.Port = strconv.FormatUint(number, 10)
+	if url.Port == specialSchemes[url.Scheme] {
+		url.Port = ""
+	}
+	return url, nil
+}
+
+// portSeparator - index of the first ':' outside brackets, -1 when there is none
+func portSeparator(authority string) int {
+	insideBrackets := false
+	for idx := 0; idx < len(authority); idx++ {
+		switch authority[idx] {
+		case '[':
+			insideBrackets = true
+		case ']':
+			insideBrackets = false
+		case ':':
+			if !insideBrackets {
+				return idx
+			}
+		}
+	}
+	return -1
+}
+
+// isWindowsDriveLetter - report whether s is an ASCII letter followed by ':' or '|'
+func isWindowsDriveLetter(s string) bool {
+	return len(s) == 2 && isASCIIAlpha(s[0]) && (s[1] == ':' || s[1] == '|')
+}
+
+// parseHost - the host parser of the URL Standard, opaque for hosts of non-special URLs
+func parseHost(input string, opaque bool) (string, error) {
+	if strings.HasPrefix(input, "[") {
+		if !strings.HasSuffix(input, "]") {
+			return "", fmt.Errorf("unterminated IPv6 address")
+		}
+		address, err := parseIPv6(input[1 : len(input)-1])
+		if err != nil {
+			return "", err
+		}
+		return "[" + serializeIPv6(address) + "]", nil
+	}
+	if opaque {
+		return parseOpaqueHost(input)
+	}
+
+	domain := percentDecode(input)
+	if !utf8.ValidString(domain) {
+		return "", fmt.Errorf("host is not UTF-8")
+	}
+	asciiDomain, err := domainToASCII(domain)
+	if err != nil {
+		return "", err
+	}
+	if idx := strings.IndexFunc(asciiDomain, isForbiddenDomainCodePoint); idx != -1 {
+		return "", fmt.Errorf("forbidden code point %q in host", asciiDomain[idx])
+	}
+	if endsInNumber(asciiDomain) {
+		address, err := parseIPv4(asciiDomain)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d.%d.%d.%d", byte(address>>24), byte(address>>16), byte(address>>8), byte(address)), nil
+	}
+	return asciiDomain, nil
+}
+
+// domainToASCII - UTS #46 ToASCII with the URL Standard flags, ASCII hosts without "xn--" labels
+// are only lowercased
+func domainToASCII(domain string) (string, error) {
+	result := strings.ToLower(domain)
+	needsIDNA := false
+	for _, r := range domain {
+		if r >= utf8.RuneSelf {
+			needsIDNA = true
+			break
+		}
+	}
+	for _, label := range strings.Split(result, ".") {
+		if strings.HasPrefix(label, "xn--") {
+			needsIDNA = true
+		}
+	}
+	if needsIDNA {
+		var err error
+		if result, err = whatwgProfile.ToASCII(domain); err != nil {
+			return "", err
+		}
+	}
+	if result == "" {
+		return "", fmt.Errorf("empty host")
+	}
+	return result, nil
+}
+
+// parseOpaqueHost - host of a non-special URL, kept as is with C0 controls and non-ASCII percent-encoded
+func parseOpaqueHost(input string) (string, error) {
+	if idx := strings.IndexFunc(input, isForbiddenHostCodePoint); idx != -1 {
+		return "", fmt.Errorf("forbidden code point %q in host", input[idx])
+	}
+	var sb strings.Builder
+	for idx := 0; idx < len(input); idx++ {
+		if c := input[idx]; c < 0x20 || c > 0x7e {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), nil
+}
+
+func isForbiddenHostCodePoint(r rune) bool {
+	switch r {
+	case 0, '\t', '\n', '\r', ' ', '#', '/', ':', '<', '>', '?', '@', '[', '\\', ']', '^', '|':
+		return true
+	}
+	return false
+}
+
+func isForbiddenDomainCodePoint(r rune) bool {
+	return isForbiddenHostCodePoint(r) || r <= 0x1f || r == '%' || r == 0x7f
+}
+
+// percentDecode - replace each "%XX" with its byte, other '%' are kept
+func percentDecode(input string) string {
+	if !strings.Contains(input, "%") {
+		return input
+	}
+	out := make([]byte, 0, len(input))
+	for idx := 0; idx < len(input); idx++ {
+		if input[idx] == '%' && idx+2 < len(input) && isASCIIHex(input[idx+1]) && isASCIIHex(input[idx+2]) {
+			value, _ := strconv.ParseUint(input[idx+1:idx+3], 16, 8)
+			out = append(out, byte(value))
+			idx += 2
+			continue
+		}
+		out = append(out, input[idx])
+	}
+	return string(out)
+}
+
+// endsInNumber - report whether the last label of host, ignoring one trailing dot, is an IPv4 number
+func endsInNumber(host string) bool {
+	parts := strings.Split(host, ".")
+	if parts[len(parts)-1] == "" {
+		if len(parts) == 1 {
+			return false
+		}
+		parts = parts[:len(parts)-1]
+	}
+	last := parts[len(parts)-1]
+	if last != "" && strings.Trim(last, "0123456789") == "" {
+		return true
+	}
+	_, err := parseIPv4Number(last)
+	return err == nil
+}
+
+// parseIPv4 - IPv4 address in the forms browsers accept: 1 to 4 parts of decimal, octal "0" or hex "0x"
+// numbers, the last filling the remaining bytes
+func parseIPv4(host string) (uint32, error) {
+	parts := strings.Split(host, ".")
+	if parts[len(parts)-1] == "" && len(parts) > 1 {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) > 4 {
+		return 0, fmt.Errorf("IPv4 address with more than four parts")
+	}
+	numbers := make([]uint64, 0, len(parts))
+	for _, part := range parts {
+		number, err := parseIPv4Number(part)
+		if err != nil {
+			return 0, err
+		}
+		numbers = append(numbers, number)
+	}
+	for _, number := range numbers[:len(numbers)-1] {
+		if number > 255 {
+			return 0, fmt.Errorf("IPv4 address part out of range")
+		}
+	}
+	last := numbers[len(numbers)-1]
+	if last >= 1<<(8*(5-len(numbers))) {
+		return 0, fmt.Errorf("IPv4 address out of range")
+	}
+	address := uint32(last)
+	for idx, number := range numbers[:len(numbers)-1] {
+		address += uint32(number) << (8 * (3 - idx))
+	}
+	return address, nil
+}
+
+func parseIPv4Number(part string) (uint64, error) {
+	if part == "" {
+		return 0, fmt.Errorf("empty IPv4 address part")
+	}
+	base := 10
+	switch {
+	case len(part) >= 2 && (strings.HasPrefix(part, "0x") || strings.HasPrefix(part, "0X")):
+		part, base = part[2:], 16
+	case len(part) >= 2 && part[0] == '0':
+		part, base = part[1:], 8
+	}
+	if part == "" {
+		return 0, nil
+	}
+	number, err := strconv.ParseUint(part, base, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		// Any value above 2^32 fails the address
+		return 1 << 32, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid IPv4 address part '%s'", part)
+	}
+	return number, nil
+}
+
+// parseIPv6 - IPv6 address pieces of the text between the brackets
+func parseIPv6(input string) ([8]uint16, error) {
+	address := [8]uint16{}
+	pieceIndex, compress, pointer := 0, -1, 0
+	at := func(idx int) byte {
+		if idx < len(input) {
+			return input[idx]
+		}
+		return 0
+	}
+	invalid := fmt.Errorf("invalid IPv6 address")
+
+	if at(pointer) == ':' {
+		if at(pointer+1) != ':' {
+			return address, invalid
+		}
+		pointer += 2
+		pieceIndex++
+		compress = pieceIndex
+	}
+	for pointer < len(input) {
+		if pieceIndex == 8 {
+			return address, invalid
+		}
+		if input[pointer] == ':' {
+			if compress != -1 {
+				return address, invalid
+			}
+			pointer++
+			pieceIndex++
+			compress = pieceIndex
+			continue
+		}
+		value, length := 0, 0
+		for length < 4 && isASCIIHex(at(pointer)) {
+			digit, _ := strconv.ParseUint(string(at(pointer)), 16, 8)
+			value = value*16 + int(digit)
+			pointer++
+			length++
+		}
+		if at(pointer) == '.' {
+			// Embedded IPv4 address in the last two pieces
+			if length == 0 || pieceIndex > 6 {
+				return address, invalid
+			}
+			pointer -= length
+			numbersSeen := 0
+			for pointer < len(input) {
+				if numbersSeen > 0 {
+					if input[pointer] != '.' || numbersSeen >= 4 {
+						return address, invalid
+					}
+					pointer++
+				}
+				if !isASCIIDigit(at(pointer)) {
+					return address, invalid
+				}
+				ipv4Piece := -1
+				for isASCIIDigit(at(pointer)) {
+					number := int(at(pointer) - '0')
+					switch ipv4Piece {
+					case -1:
+						ipv4Piece = number
+					case 0:
+						return address, invalid
+					default:
+						ipv4Piece = ipv4Piece*10 + number
+					}
+					if ipv4Piece > 255 {
+						return address, invalid
+					}
+					pointer++
+				}
+				address[pieceIndex] = address[pieceIndex]*0x100 + uint16(ipv4Piece)
+				numbersSeen++
+				if numbersSeen == 2 || numbersSeen == 4 {
+					pieceIndex++
+				}
+			}
+			if numbersSeen != 4 {
+				return address, invalid
+			}
+			break
+		}
+		if at(pointer) == ':' {
+			pointer++
+			if pointer == len(input) {
+				return address, invalid
+			}
+		} else if pointer < len(input) {
+			return address, invalid
+		}
+		address[pieceIndex] = uint16(value)
+		pieceIndex++
+	}
+
+	if compress != -1 {
+		swaps := pieceIndex - compress
+		pieceIndex = 7
+		for pieceIndex != 0 && swaps > 0 {
+			address[pieceIndex], address[compress+swaps-1] = address[compress+swaps-1], address[pieceIndex]
+			pieceIndex--
+			swaps--
+		}
+	} else if pieceIndex != 8 {
+		return address, invalid
+	}
+	return address, nil
+}
+
+// serializeIPv6 - lowercase hex pieces with the first longest run of two or more zero pieces as "::"
+func serializeIPv6(address [8]uint16) string {
+	compress, longest := -1, 1
+	for idx := 0; idx < 8; {
+		if address[idx] != 0 {
+			idx++
+			continue
+		}
+		end := idx
+		for end < 8 && address[end] == 0 {
+			end++
+		}
+		if end-idx > longest {
+			compress, longest = idx, end-idx
+		}
+		idx = end
+	}
+
+	var sb strings.Builder
+	for idx := 0; idx < 8; idx++ {
+		if idx == compress {
+			if idx == 0 {
+				sb.WriteString("::")
+			} else {
+				sb.WriteString(":")
+			}
+			idx += longest - 1
+			continue
+		}
+		sb.WriteString(strconv.FormatUint(uint64(address[idx]), 16))
+		if idx != 7 {
+			sb.WriteString(":")
+		}
+	}
+	return sb.String()
+}
+
+func isASCIIAlpha(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isASCIIDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isASCIIHex(c byte) bool {
+	return isASCIIDigit(c) || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+// extractWHATWG - Extract of the host ParseWHATWG finds in input, or in "http://" + input without a scheme
+func (tlde *TLDExtract) extractWHATWG(input string) *Result {
+	if !hasScheme(input) {
+		input = "http://" + input
+	}
+	url, err := ParseWHATWG(input, "")
+	if err != nil {
+		var urlErr *WHATWGError
+		errors.As(err, &urlErr)
+		return rejected(Malformed, ReasonInvalidURL, urlErr.Host)
+	}
+
+	if tlde.Debug {
+		fmt.Printf("%s;%s\n", url.Host, input)
+	}
+
+	if url.Host == "" {
+		return rejected(NoHost, ReasonEmptyInput, "")
+	}
+	// A browser resolves "example.com." as "example.com", the root label is not part of the suffix
+	host := strings.TrimSuffix(strings.Trim(url.Host, "[]"), ".")
+	return tlde.extract(host)
+}
+
+// hasScheme - report whether input starts with a scheme rather than a host and port, as in
+// "localhost:8080" or "example.com:443/path". Special schemes and schemes followed by "//" always
+// count, others only when the text after the ':' is not a port.
+func hasScheme(input string) bool {
+	scheme, rest, found := cutScheme(strings.TrimLeftFunc(input, func(r rune) bool { return r <= ' ' }))
+	if !found {
+		return false
+	}
+	if isSpecial(scheme) || strings.HasPrefix(rest, "//") {
+		return true
+	}
+	port := rest
+	if end := strings.IndexAny(rest, `/\?#`); end != -1 {
+		port = rest[:end]
+	}
+	return strings.Trim(port, "0123456789") != ""
+}
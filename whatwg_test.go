@@ -0,0 +1,101 @@
+package tldextract
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// urlTestCase - entry of the web-platform-tests urltestdata.json format, reduced to the fields ParseWHATWG covers
+type urlTestCase struct {
+	Input      string  `json:"input"`
+	Base       *string `json:"base"`
+	Failure    bool    `json:"failure"`
+	RelativeTo string  `json:"relativeTo"`
+	Protocol   string  `json:"protocol"`
+	Hostname   string  `json:"hostname"`
+	Port       string  `json:"port"`
+}
+
+func Test_WHATWG_urltestdata(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := os.ReadFile("test/urltestdata.json")
+	assert.Nil(err, "Error nil")
+	entries := []json.RawMessage{}
+	assert.Nil(json.Unmarshal(data, &entries), "Error nil")
+
+	count := 0
+	for _, entry := range entries {
+		tc := urlTestCase{}
+		if json.Unmarshal(entry, &tc) != nil {
+			// Comment strings
+			continue
+		}
+		if tc.RelativeTo != "" {
+			// Failures checked against a family of bases the file does not list
+			continue
+		}
+		base := ""
+		if tc.Base != nil {
+			base = *tc.Base
+		}
+		count++
+
+		actual, err := ParseWHATWG(tc.Input, base)
+		if tc.Failure {
+			assert.NotNil(err, "%q against %q should fail", tc.Input, base)
+			continue
+		}
+		if assert.Nil(err, "%q against %q", tc.Input, base) {
+			assert.Equal(tc.Protocol, actual.Scheme+":", "protocol of %q against %q", tc.Input, base)
+			assert.Equal(tc.Hostname, actual.Host, "hostname of %q against %q", tc.Input, base)
+			assert.Equal(tc.Port, actual.Port, "port of %q against %q", tc.Input, base)
+		}
+	}
+	assert.Greater(count, 0, "test cases read")
+}
+
+func Test_Extract_WHATWG(t *testing.T) {
+	assert := assert.New(t)
+
+	tld, err := NewWithOptions(WithCacheFile("test/tld.cache"), WithRefreshPolicy(RefreshNever), WithWHATWG(true))
+	assert.Nil(err, "Error nil")
+
+	testCases := []struct {
+		Url         string
+		Expected    Result
+		Description string
+	}{
+		{Url: "http://example.com/path@evil.com", Expected: Result{Flag: Domain, Domain: "example", Tld: "com"}, Description: "'@' in the path"},
+		{Url: "https:\\\\www.example.com\\login", Expected: Result{Flag: Domain, SubDomain: "www", Domain: "example", Tld: "com"}, Description: "backslashes"},
+		{Url: "http://ex\tam\nple.com/", Expected: Result{Flag: Domain, Domain: "example", Tld: "com"}, Description: "tab and newline"},
+		{Url: "http://www.ex%61mple.com/", Expected: Result{Flag: Domain, SubDomain: "www", Domain: "example", Tld: "com"}, Description: "percent-encoded host"},
+		{Url: "www.example.com/path", Expected: Result{Flag: Domain, SubDomain: "www", Domain: "example", Tld: "com"}, Description: "no scheme"},
+		{Url: "http://0x7f.1/", Expected: Result{Flag: IPv4, Domain: "127.0.0.1"}, Description: "hex IPv4"},
+		{Url: "http://3232235521/", Expected: Result{Flag: IPv4, Domain: "192.168.0.1"}, Description: "IPv4 as one number"},
+		{Url: "http://[::1]:8080/", Expected: Result{Flag: IPv6, Domain: "::1"}, Description: "IPv6"},
+		{Url: "mailto:user@example.com", Expected: Result{Flag: NoHost}, Description: "no host"},
+		{Url: "www.example.com:8080/path", Expected: Result{Flag: Domain, SubDomain: "www", Domain: "example", Tld: "com"}, Description: "no scheme with port and path"},
+		{Url: "example.com:443", Expected: Result{Flag: Domain, Domain: "example", Tld: "com"}, Description: "no scheme with port"},
+		{Url: "localhost:8080", Expected: Result{Flag: UnknownTLD}, Description: "no scheme, host without suffix"},
+		{Url: "example.com:", Expected: Result{Flag: Domain, Domain: "example", Tld: "com"}, Description: "no scheme with empty port"},
+		{Url: "http://example.com./", Expected: Result{Flag: Domain, Domain: "example", Tld: "com"}, Description: "trailing root dot"},
+		{Url: "http://www.example.com.:8080/", Expected: Result{Flag: Domain, SubDomain: "www", Domain: "example", Tld: "com"}, Description: "trailing root dot with port"},
+		{Url: "http://exa mple.com/", Expected: Result{Flag: Malformed}, Description: "forbidden code point"},
+	}
+
+	for _, tc := range testCases {
+		assertResult(t, tc.Url, &tc.Expected, tld.Extract(tc.Url), tc.Description)
+	}
+
+	_, err = tld.Parse("http://exa mple.com/")
+	assert.EqualError(err, "'http://exa mple.com/': invalid host 'exa mple.com'", "reason")
+	assert.True(tld.ICANNOnly().WHATWG, "ICANNOnly copy keeps the mode")
+	assert.Equal("localhost", tld.Extract("localhost:8080").Label, "host of input without scheme")
+
+	tld.WHATWG = false
+	assert.Equal("evil", tld.Extract("http://example.com/path@evil.com").Domain, "lenient parsing")
+}